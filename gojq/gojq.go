@@ -1,8 +1,11 @@
 package gojq
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
 
 	"github.com/bartdeboer/pipeline"
 	"github.com/itchyny/gojq"
@@ -15,6 +18,17 @@ import (
 // [github.com/itchyny/gojq], whose documentation explains the differences
 // between it and standard JQ.
 func JQ(query string) pipeline.Program {
+	return jq(query, false)
+}
+
+// JQRaw is like [JQ], but produces results that are strings as they are,
+// rather than as quoted JSON strings, like jq -r. Other results are produced
+// as JSON.
+func JQRaw(query string) pipeline.Program {
+	return jq(query, true)
+}
+
+func jq(query string, raw bool) pipeline.Program {
 	p := pipeline.NewBaseProgram()
 	p.StartFn = func() error {
 		q, err := gojq.Parse(query)
@@ -35,6 +49,10 @@ func JQ(query string) pipeline.Program {
 			if err, ok := v.(error); ok {
 				return err
 			}
+			if s, ok := v.(string); ok && raw {
+				fmt.Fprintln(p.Stdout, s)
+				continue
+			}
 			result, err := gojq.Marshal(v)
 			if err != nil {
 				return err
@@ -44,3 +62,61 @@ func JQ(query string) pipeline.Program {
 	}
 	return p
 }
+
+// JQLines is like [JQ], but reads the pipe's contents as JSON Lines: each
+// line is decoded as an independent JSON value, and query is executed on each
+// in turn, producing all the results. Blank lines are ignored. If a line
+// isn't valid JSON, the pipe's error status is set, and no further lines are
+// processed.
+func JQLines(query string) pipeline.Program {
+	return jqLines(query, false)
+}
+
+// JQLinesSkipInvalid is like [JQLines], but ignores lines that aren't valid
+// JSON, rather than setting the pipe's error status.
+func JQLinesSkipInvalid(query string) pipeline.Program {
+	return jqLines(query, true)
+}
+
+func jqLines(query string, skipInvalid bool) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	q, err := gojq.Parse(query)
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		scanner := bufio.NewScanner(p.Stdin)
+		scanner.Buffer(make([]byte, 4096), math.MaxInt)
+		for n := 1; scanner.Scan(); n++ {
+			line := scanner.Bytes()
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			var input interface{}
+			if err := json.Unmarshal(line, &input); err != nil {
+				if skipInvalid {
+					continue
+				}
+				return p.Exit(fmt.Errorf("line %d: %w", n, err))
+			}
+			iter := q.Run(input)
+			for {
+				v, ok := iter.Next()
+				if !ok {
+					break
+				}
+				if err, ok := v.(error); ok {
+					return p.Exit(err)
+				}
+				result, err := gojq.Marshal(v)
+				if err != nil {
+					return p.Exit(err)
+				}
+				fmt.Fprintln(p.Stdout, string(result))
+			}
+		}
+		return scanner.Err()
+	}
+	return p
+}
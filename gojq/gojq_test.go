@@ -0,0 +1,68 @@
+package gojq_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bartdeboer/pipeline"
+	"github.com/bartdeboer/script/v2/gojq"
+)
+
+func TestJQLinesRunsQueryOnEachLineSkippingBlankLines(t *testing.T) {
+	t.Parallel()
+	input := "{\"a\":1,\"b\":[2,3]}\n\n  \n{\"a\":4,\"b\":[]}\n"
+	p := pipeline.NewPipeline().WithReader(strings.NewReader(input))
+	got, err := p.Pipe(gojq.JQLines(".a, .b[]")).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "1\n2\n3\n4\n"
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestJQLinesSetsErrorWithLineNumberAndStopsAtFirstInvalidLine(t *testing.T) {
+	t.Parallel()
+	input := "{\"a\":1}\n\n{bad\n{\"a\":2}\n"
+	p := pipeline.NewPipeline().WithReader(strings.NewReader(input))
+	got, err := p.Pipe(gojq.JQLines(".a")).String()
+	if err == nil {
+		t.Fatal("want error given invalid JSON line")
+	}
+	if !strings.HasPrefix(err.Error(), "line 3: ") {
+		t.Errorf("want error prefixed with line number, got %q", err)
+	}
+	want := "1\n"
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestJQLinesSkipInvalidContinuesPastInvalidLines(t *testing.T) {
+	t.Parallel()
+	input := "{\"a\":1}\n{bad\nnot json\n{\"a\":2}\n"
+	p := pipeline.NewPipeline().WithReader(strings.NewReader(input))
+	got, err := p.Pipe(gojq.JQLinesSkipInvalid(".a")).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "1\n2\n"
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestJQRawOutputsStringsUnquotedAndOtherValuesAsJSON(t *testing.T) {
+	t.Parallel()
+	input := `{"s":"say \"hi\"","n":1.5,"o":{"k":"v"},"a":[1,"x"]}`
+	p := pipeline.NewPipeline().WithReader(strings.NewReader(input))
+	got, err := p.Pipe(gojq.JQRaw(".s, .n, .o, .a")).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "say \"hi\"\n1.5\n{\"k\":\"v\"}\n[1,\"x\"]\n"
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,9 @@
+module github.com/bartdeboer/script/v2/html
+
+go 1.22.1
+
+require (
+	github.com/andybalholm/cascadia v1.3.2
+	github.com/bartdeboer/pipeline v0.0.3
+	golang.org/x/net v0.33.0
+)
@@ -0,0 +1,107 @@
+package html
+
+import (
+	"io"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/bartdeboer/pipeline"
+	"golang.org/x/net/html"
+)
+
+// StripHTML reads the pipe's contents as HTML and produces only its text
+// content, with all tags removed and character references such as &amp;
+// and &lt; decoded. The contents of script and style elements, and comments,
+// are removed entirely. Whitespace is left as it is in the input.
+func StripHTML() pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		z := html.NewTokenizer(p.Stdin)
+		skip := ""
+		for {
+			switch z.Next() {
+			case html.ErrorToken:
+				if err := z.Err(); err != io.EOF {
+					return p.Exit(err)
+				}
+				return nil
+			case html.StartTagToken:
+				name, _ := z.TagName()
+				if tag := string(name); tag == "script" || tag == "style" {
+					skip = tag
+				}
+			case html.EndTagToken:
+				name, _ := z.TagName()
+				if strings.EqualFold(string(name), skip) {
+					skip = ""
+				}
+			case html.TextToken:
+				if skip == "" {
+					if _, err := p.Stdout.Write(z.Text()); err != nil {
+						return p.Exit(err)
+					}
+				}
+			}
+		}
+	}
+	return p
+}
+
+// HTMLSelect reads the pipe's contents as HTML and produces the text content
+// of each element matching the CSS selector, one per line, in document
+// order. Runs of whitespace within the text, including newlines, are
+// collapsed to single spaces. If selector is invalid, the pipe's error
+// status is set.
+func HTMLSelect(selector string) pipeline.Program {
+	return htmlSelect(selector, func(w io.Writer, n *html.Node) error {
+		var text strings.Builder
+		appendText(&text, n)
+		_, err := io.WriteString(w, strings.Join(strings.Fields(text.String()), " ")+"\n")
+		return err
+	})
+}
+
+// HTMLSelectHTML is like [HTMLSelect], but produces the outer HTML of each
+// matching element instead of its text. Any newlines within an element are
+// kept.
+func HTMLSelectHTML(selector string) pipeline.Program {
+	return htmlSelect(selector, func(w io.Writer, n *html.Node) error {
+		if err := html.Render(w, n); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	})
+}
+
+func htmlSelect(selector string, write func(io.Writer, *html.Node) error) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	sel, err := cascadia.Compile(selector)
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		doc, err := html.Parse(p.Stdin)
+		if err != nil {
+			return p.Exit(err)
+		}
+		for _, n := range cascadia.QueryAll(doc, sel) {
+			if err := write(p.Stdout, n); err != nil {
+				return p.Exit(err)
+			}
+		}
+		return nil
+	}
+	return p
+}
+
+// appendText appends the text content of n and its descendants to b.
+func appendText(b *strings.Builder, n *html.Node) {
+	if n.Type == html.TextNode {
+		b.WriteString(n.Data)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		appendText(b, c)
+	}
+}
@@ -0,0 +1,62 @@
+package html_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bartdeboer/pipeline"
+	"github.com/bartdeboer/script/v2/html"
+)
+
+func TestStripHTMLOutputsOnlyTextContent(t *testing.T) {
+	t.Parallel()
+	input := `<html><head><title>Fish &amp; Chips</title><style>p { color: red }</style></head>` +
+		`<body><!-- note --><p>1 &lt; 2</p><script>alert("hi")</script><p>Done</p></body></html>`
+	want := "Fish & Chips1 < 2Done"
+	p := pipeline.NewPipeline().WithReader(strings.NewReader(input))
+	got, err := p.Pipe(html.StripHTML()).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestHTMLSelectOutputsTextOfMatchingElements(t *testing.T) {
+	t.Parallel()
+	input := `<ul><li><a href="/a">First
+		link</a></li><li><a>No href</a></li><li><a href="/b"><b>Second</b> &amp; last</a></li></ul>`
+	want := "First link\nSecond & last\n"
+	p := pipeline.NewPipeline().WithReader(strings.NewReader(input))
+	got, err := p.Pipe(html.HTMLSelect("a[href]")).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestHTMLSelectHTMLOutputsOuterHTMLOfMatchingElements(t *testing.T) {
+	t.Parallel()
+	input := `<p>One <a href="/a">link</a></p><p>Two</p>`
+	want := "<a href=\"/a\">link</a>\n"
+	p := pipeline.NewPipeline().WithReader(strings.NewReader(input))
+	got, err := p.Pipe(html.HTMLSelectHTML("p > a")).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestHTMLSelectSetsErrorGivenInvalidSelector(t *testing.T) {
+	t.Parallel()
+	p := pipeline.NewPipeline().WithReader(strings.NewReader("<p>x</p>"))
+	_, err := p.Pipe(html.HTMLSelect("p[")).String()
+	if err == nil {
+		t.Error("want error given invalid selector")
+	}
+}
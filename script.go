@@ -1,21 +1,42 @@
 package script
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bartdeboer/pipeline"
 	"github.com/bartdeboer/pipeline/std"
+	xstd "github.com/bartdeboer/script/v2/std"
 )
 
 type Pipe struct {
 	std.Pipeline[*Pipe]
 	stdout io.Writer
+	ctx    context.Context
+	env    map[string]string
+	dir    string
 
-	httpClient *http.Client
+	httpClient     *http.Client
+	header         http.Header
+	requestLimiter *xstd.RateLimiter
+	requestTimeout time.Duration
+	retryAttempts  int
+	retryBackoff   time.Duration
+
+	progress func(written int64)
+	written  int64
 }
 
 func NewPipe() *Pipe {
@@ -28,6 +49,11 @@ func NewPipe() *Pipe {
 	return p
 }
 
+// NewPipelineContext creates a pipeline whose stages are aborted when ctx is cancelled
+func NewPipelineContext(ctx context.Context) *Pipe {
+	return NewPipe().WithContext(ctx)
+}
+
 // For backwards compatibility
 func (p *Pipe) Filter(filter func(r io.Reader, w io.Writer) error) *Pipe {
 	b := pipeline.NewBaseProgram()
@@ -42,8 +68,35 @@ func (p *Pipe) FilterScan(filter func(string, io.Writer)) *Pipe {
 	return p.Scanner(filter)
 }
 
+// Run adds programs to the pipe, then copies its output to the pipe's standard output and
+// returns the number of bytes written, reporting progress as set by WithProgress
+func (p *Pipe) Run(programs ...pipeline.Program) (int64, error) {
+	for _, program := range programs {
+		p.Pipe(program)
+	}
+	if p.progress == nil {
+		written, err := p.Pipeline.Run()
+		p.written = written
+		return written, err
+	}
+	r := &progressReader{r: p.Pipeline, fn: p.progress, next: progressInterval}
+	written, err := io.Copy(p.stdout, r)
+	p.written = written
+	p.progress(written)
+	if err != nil {
+		p.SetError(err)
+	}
+	return written, p.Error()
+}
+
+// Written returns the number of bytes the last call to Run or Stdout copied to the pipe's
+// standard output, or zero if neither has been called
+func (p *Pipe) Written() int64 {
+	return p.written
+}
+
 func (p *Pipe) Stdout() (int, error) {
-	n64, err := p.Pipeline.Run()
+	n64, err := p.Run()
 	n := int(n64)
 	if int64(n) != n64 {
 		return 0, fmt.Errorf("length %d overflows int", n64)
@@ -58,6 +111,11 @@ func Args() *Pipe {
 	return Slice(os.Args[1:])
 }
 
+// Delete creates a pipeline with a DELETE HTTP request
+func Delete(url string) *Pipe {
+	return NewPipe().Delete(url)
+}
+
 // Do creates a pipeline with an HTTP request
 func Do(req *http.Request) *Pipe {
 	return NewPipe().Do(req)
@@ -83,27 +141,97 @@ func FindFiles(dir string) *Pipe {
 	return NewPipe().Pipe(std.FindFiles(dir))
 }
 
+// FindFilesDepth creates a pipeline with the files found in dir, descending at most
+// maxDepth levels of subdirectories
+func FindFilesDepth(dir string, maxDepth int) *Pipe {
+	return NewPipe().Pipe(xstd.FindFilesDepth(dir, maxDepth))
+}
+
+// FindFilesInfo creates a pipeline with a tab-separated line for each file found in dir,
+// giving its path, size, mode and modification time
+func FindFilesInfo(dir string) *Pipe {
+	return NewPipe().Pipe(xstd.FindFilesInfo(dir))
+}
+
+// FindFilesMatch creates a pipeline with the files found in dir whose base name matches
+// the shell pattern
+func FindFilesMatch(dir, pattern string) *Pipe {
+	return NewPipe().Pipe(xstd.FindFilesMatch(dir, pattern))
+}
+
+// FindFilesRegexp creates a pipeline with the files found in dir whose path matches the
+// compiled regexp re
+func FindFilesRegexp(dir string, re *regexp.Regexp) *Pipe {
+	return NewPipe().Pipe(xstd.FindFilesRegexp(dir, re))
+}
+
+// FollowFile creates a pipeline with the file contents, followed by any data appended
+// to the file, like tail -f. The pipeline never ends; use FollowFileContext to stop it
+func FollowFile(path string) *Pipe {
+	return FollowFileContext(context.Background(), path)
+}
+
+// FollowFileContext is like FollowFile, but stops following the file when ctx is cancelled
+func FollowFileContext(ctx context.Context, path string) *Pipe {
+	return NewPipe().Pipe(xstd.FollowFile(ctx, path))
+}
+
 // Do creates a pipeline with a GET HTTP request
 func Get(url string) *Pipe {
 	return NewPipe().Get(url)
 }
 
+// GetAll creates a pipeline with the responses to GET HTTP requests for each of urls,
+// made with at most concurrency requests at once, in the order of urls
+func GetAll(urls []string, concurrency int) *Pipe {
+	return NewPipe().GetAll(urls, concurrency)
+}
+
 func IfExists(path string) *Pipe {
 	p := NewPipe()
 	p.Pipeline.SetExitOnError(true)
 	return p.Pipe(std.IfExists(path))
 }
 
+// JoinOn creates a pipeline with the inner join of the lines of left and right, where column
+// leftCol of a left line equals column rightCol of a right line, joining each matching pair
+// with sep. The whole of right is read first, and each right line matching a left line is
+// output in turn
+func JoinOn(left, right *Pipe, leftCol, rightCol int, sep string) *Pipe {
+	return left.Pipe(xstd.JoinOn(right, leftCol, rightCol, sep))
+}
+
 // ListFiles creates a pipeline with the file listing of path
 func ListFiles(path string) *Pipe {
 	return NewPipe().Pipe(std.ListFiles(path))
 }
 
+// ListFilesGlob creates a pipeline with the files matching pattern, which may contain the
+// recursive wildcard **, in sorted order
+func ListFilesGlob(pattern string) *Pipe {
+	return NewPipe().Pipe(xstd.ListFilesGlob(pattern))
+}
+
 // Do creates a pipeline with a POST HTTP request
 func Post(url string) *Pipe {
 	return NewPipe().Post(url)
 }
 
+// Patch creates a pipeline with a PATCH HTTP request
+func Patch(url string) *Pipe {
+	return NewPipe().Patch(url)
+}
+
+// PostJSON creates a pipeline with a POST HTTP request whose body is v encoded as JSON
+func PostJSON(url string, v any) *Pipe {
+	return NewPipe().PostJSON(url, v)
+}
+
+// Put creates a pipeline with a PUT HTTP request
+func Put(url string) *Pipe {
+	return NewPipe().Put(url)
+}
+
 // Slice creates a pipeline with a new line for each slice item
 func Slice(s []string) *Pipe {
 	return Echo(strings.Join(s, "\n") + "\n")
@@ -116,20 +244,168 @@ func Stdin() *Pipe {
 
 // Program shortcuts:
 
+// AggregateBy reads the input, groups the lines by column keyCol, and outputs each key with the
+// sum, avg, min, max or count (as given by op) of the values in column valCol, sorted by key
+func (p *Pipe) AggregateBy(keyCol, valCol int, op string) *Pipe {
+	return p.Pipe(xstd.AggregateBy(keyCol, valCol, op))
+}
+
 // AppendFile reads the input and appends it to the file path, creating it if necessary,
 // and outputs the number of bytes successfully written
 func (p *Pipe) AppendFile(path string) (int64, error) {
 	return p.Pipe(std.AppendFile(path)).Int64()
 }
 
+// AppendFileMode is like AppendFile, but creates the file with the permissions perm, or
+// changes the permissions of an existing file to perm
+func (p *Pipe) AppendFileMode(path string, perm os.FileMode) (int64, error) {
+	return p.Pipe(xstd.AppendFileMode(path, perm)).Int64()
+}
+
+// Base64Decode reads the input as standard base64 and outputs the decoded data
+func (p *Pipe) Base64Decode() *Pipe {
+	return p.Pipe(xstd.Base64Decode())
+}
+
+// Base64DecodeWith reads the input as base64 in the encoding enc and outputs the decoded data
+func (p *Pipe) Base64DecodeWith(enc *base64.Encoding) *Pipe {
+	return p.Pipe(xstd.Base64DecodeWith(enc))
+}
+
+// Base64Encode reads the input and outputs it encoded as standard base64
+func (p *Pipe) Base64Encode() *Pipe {
+	return p.Pipe(xstd.Base64Encode())
+}
+
+// Base64EncodeWith reads the input and outputs it encoded as base64 in the encoding enc
+func (p *Pipe) Base64EncodeWith(enc *base64.Encoding) *Pipe {
+	return p.Pipe(xstd.Base64EncodeWith(enc))
+}
+
+// Basename reads each line as a file path and outputs each path with any leading directory components removed
+func (p *Pipe) Basename() *Pipe {
+	return p.Pipe(std.Basename())
+}
+
+// Bucketize reads numbers from column col of each line and outputs the number of values
+// in each bucket of width bucketSize
+func (p *Pipe) Bucketize(col int, bucketSize float64) *Pipe {
+	return p.Pipe(xstd.Bucketize(col, bucketSize))
+}
+
+// ByteRange reads the input, skips the first offset bytes and outputs the next length bytes,
+// or the rest of the input if length is negative
+func (p *Pipe) ByteRange(offset, length int64) *Pipe {
+	return p.Pipe(xstd.ByteRange(offset, length))
+}
+
+// CanonicalJSON reads the input as JSON and outputs it with sorted keys and no insignificant whitespace
+func (p *Pipe) CanonicalJSON() *Pipe {
+	return p.Pipe(xstd.CanonicalJSON())
+}
+
+// Column reads each line and outputs column col, where columns are whitespace delimited and the first column is column 1
+func (p *Pipe) Column(col int) *Pipe {
+	return p.Pipe(std.Column(col))
+}
+
+// ColumnDelim reads each line and outputs column col, where columns are delimited by delim
+// and the first column is column 1
+func (p *Pipe) ColumnDelim(col int, delim string) *Pipe {
+	return p.Pipe(xstd.ColumnDelim(col, delim))
+}
+
+// Concat reads each line as a file path and outputs the file contents
+func (p *Pipe) Concat() *Pipe {
+	return p.Pipe(std.Concat())
+}
+
+// ContentHash reads the input and returns a stable hash of it suitable for caching,
+// currently its hex-encoded SHA-256 hash
+func (p *Pipe) ContentHash() (string, error) {
+	return p.SHA256Sum()
+}
+
+// ConvertUnits reads numeric values from column col of each line and outputs the line with
+// the value converted from unit from to unit to, such as "MiB" to "kB", or "C" to "F"
+func (p *Pipe) ConvertUnits(col int, from, to string) *Pipe {
+	return p.Pipe(xstd.ConvertUnits(col, from, to))
+}
+
 // CountLines returns the number of lines of input, or an error.
 func (p *Pipe) CountLines() (int, error) {
 	return p.Pipe(std.CountLines()).Int()
 }
 
+// CSV reads the input as comma-separated values and outputs the given 1-indexed fields of
+// each record joined by tabs, or all fields if none are given
+func (p *Pipe) CSV(fields ...int) *Pipe {
+	return p.Pipe(xstd.CSV(fields...))
+}
+
+// CSVDelim is like CSV, but with fields delimited by delim instead of a comma
+func (p *Pipe) CSVDelim(delim rune, fields ...int) *Pipe {
+	return p.Pipe(xstd.CSVDelim(delim, fields...))
+}
+
+// CSVToJSON reads the input as comma-separated values and outputs each record as a line of
+// JSON: an object keyed by the first record if headers is true, or an array otherwise
+func (p *Pipe) CSVToJSON(headers bool) *Pipe {
+	return p.Pipe(xstd.CSVToJSON(headers))
+}
+
+// Cut reads each line and outputs the fields selected by ranges (such as "1,3-5,7-"),
+// where fields are delimited by delim, rejoined by delim
+func (p *Pipe) Cut(ranges string, delim string) *Pipe {
+	return p.Pipe(xstd.Cut(ranges, delim))
+}
+
+// DataURI reads the input and outputs it as a base64-encoded data URI with the given MIME type
+func (p *Pipe) DataURI(mimeType string) *Pipe {
+	return p.Pipe(xstd.DataURI(mimeType))
+}
+
+// Dedup reads the input and outputs each distinct line once, in the order first seen
+func (p *Pipe) Dedup() *Pipe {
+	return p.Pipe(xstd.Dedup())
+}
+
+// Delete reads the input as the request body, sends a DELETE request and outputs the response
+func (p *Pipe) Delete(url string) *Pipe {
+	return p.Pipe(xstd.Delete(url, p.doer()))
+}
+
+// DetectGaps reads an increasing integer column col and outputs the range of values missing
+// wherever it jumps by more than 1
+func (p *Pipe) DetectGaps(col int) *Pipe {
+	return p.Pipe(xstd.DetectGaps(col))
+}
+
+// DiffFile reads the input and outputs a unified diff against the contents of the file path,
+// or nothing if they are identical
+func (p *Pipe) DiffFile(path string) *Pipe {
+	return p.Pipe(xstd.DiffFile(path, false))
+}
+
+// DiffFileStrict is like DiffFile, but also sets the pipe's error status if the contents differ
+func (p *Pipe) DiffFileStrict(path string) *Pipe {
+	return p.Pipe(xstd.DiffFile(path, true))
+}
+
+// Dirname reads each line as a file path and outputs each path with just the leading directory remaining
+func (p *Pipe) Dirname() *Pipe {
+	return p.Pipe(std.Dirname())
+}
+
+// DistinctURLs reads each line as a URL and outputs each distinct URL once, ignoring
+// the order of query parameters
+func (p *Pipe) DistinctURLs() *Pipe {
+	return p.Pipe(xstd.DistinctURLs())
+}
+
 // Get reads the input as the request body, sends the request and outputs the response
 func (p *Pipe) Do(req *http.Request) *Pipe {
-	return p.Pipe(std.Do(req, p.httpClient))
+	return p.Pipe(xstd.Do(req, p.doer()))
 }
 
 // Exec executes cmdLine using sh/shell, using input as stdin and outputs the result
@@ -143,9 +419,175 @@ func (p *Pipe) Do(req *http.Request) *Pipe {
 // 	return p.Pipe(shell.ExecForEach(cmdLine))
 // }
 
+// Deprecated: use [Pipe.FilterLine] or [Pipe.FilterScan] instead
+func (p *Pipe) EachLine(process func(string, *strings.Builder)) *Pipe {
+	return p.Pipe(std.EachLine(process))
+}
+
+// Echo ignores its input and outputs string s
+func (p *Pipe) Echo(s string) *Pipe {
+	return p.Pipe(std.Echo(s))
+}
+
+// Exec executes the command with name and arguments, using input as stdin and outputs the result
+func (p *Pipe) Exec(name string, arg ...string) *Pipe {
+	return p.Pipe(xstd.Command(p.command(name, arg...)))
+}
+
+// ExecForEach calls builder for each line of input, executing the resulting command
+// with name and arguments, and outputs the combined result
+func (p *Pipe) ExecForEach(builder func(line string) (string, []string)) *Pipe {
+	command := p.commander()
+	return p.Pipe(xstd.CommandForEach(func(line string) *exec.Cmd {
+		name, arg := builder(line)
+		return command(name, arg...)
+	}))
+}
+
+// ExecForEachParallelFunc is like ExecForEach, but runs up to workers commands at once, still
+// outputting their results in the order of the input lines. The template form taking a
+// command line, shell.ExecForEachParallel, lives in the shell module, which this module
+// doesn't depend on, so here the commands are built by builder instead
+func (p *Pipe) ExecForEachParallelFunc(builder func(line string) (string, []string), workers int) *Pipe {
+	command := p.commander()
+	return p.Pipe(xstd.CommandForEachParallel(func(line string) *exec.Cmd {
+		name, arg := builder(line)
+		return command(name, arg...)
+	}, workers))
+}
+
+// ExecTagged executes the command with name and arguments, using input as stdin and
+// outputs its stdout and stderr lines prefixed with "O:" and "E:" respectively.
+// To tag the output of a command line instead, pipe to shell.ExecTagged from
+// the shell module, which this module doesn't depend on.
+func (p *Pipe) ExecTagged(name string, arg ...string) *Pipe {
+	return p.Pipe(xstd.CommandTagged(p.command(name, arg...)))
+}
+
+// ExtractEmails reads the input and outputs every email address found, one per line
+func (p *Pipe) ExtractEmails() *Pipe {
+	return p.Pipe(xstd.ExtractEmails())
+}
+
+// ExtractURLs reads the input and outputs every http or https URL found, one per line
+func (p *Pipe) ExtractURLs() *Pipe {
+	return p.Pipe(xstd.ExtractURLs())
+}
+
+// FilterLine reads the input, calls the function filter on each line and outputs the result
+func (p *Pipe) FilterLine(filter func(string) string) *Pipe {
+	return p.Pipe(std.FilterLine(filter))
+}
+
+// First reads the input and outputs only the first n number of lines
+func (p *Pipe) First(n int) *Pipe {
+	return p.Pipe(std.First(n))
+}
+
+// FirstN reads the input, writes only the first n number of lines to the pipe's standard output,
+// and returns the number of lines actually written, which is less than n if the input ran out
+func (p *Pipe) FirstN(n int) (int, error) {
+	lines := 0
+	_, err := p.Pipe(std.First(n)).Scanner(func(line string, w io.Writer) {
+		lines++
+		fmt.Fprintln(w, line)
+	}).Stdout()
+	return lines, err
+}
+
+// FirstByColumn reads the input and outputs only the first line for each distinct value of
+// column col, in their original order
+func (p *Pipe) FirstByColumn(col int) *Pipe {
+	return p.Pipe(xstd.FirstByColumn(col))
+}
+
+// FirstMatchEach reads the input and outputs the first line containing each of patterns,
+// stopping once all of them have been found
+func (p *Pipe) FirstMatchEach(patterns ...string) *Pipe {
+	return p.Pipe(xstd.FirstMatchEach(patterns...))
+}
+
+// Freq reads the input and outputs only the unique lines, each prefixed with
+// a frequency count, in descending numerical order
+func (p *Pipe) Freq() *Pipe {
+	return p.Pipe(std.Freq())
+}
+
+// FreqCSV reads the input and outputs only the unique lines, each prefixed with
+// a frequency count, as "count,value" CSV records in descending numerical order
+func (p *Pipe) FreqCSV() *Pipe {
+	return p.Pipe(xstd.FreqCSV())
+}
+
 // Get reads the input as the request body, sends a GET request and outputs the response
 func (p *Pipe) Get(url string) *Pipe {
-	return p.Pipe(std.Get(url, p.httpClient))
+	return p.Pipe(xstd.Get(url, p.doer()))
+}
+
+// GetAll ignores its input, sends GET requests for each of urls with at most concurrency
+// requests at once, and outputs the responses in the order of urls
+func (p *Pipe) GetAll(urls []string, concurrency int) *Pipe {
+	return p.Pipe(xstd.GetAll(urls, concurrency, p.doer()))
+}
+
+// Gunzip reads the input as gzip data and outputs the decompressed data
+func (p *Pipe) Gunzip() *Pipe {
+	return p.Pipe(xstd.Gunzip())
+}
+
+// Gzip reads the input and outputs it compressed as gzip data at the given level
+func (p *Pipe) Gzip(level int) *Pipe {
+	return p.Pipe(xstd.Gzip(level))
+}
+
+// HashChanged reads the input and reports whether its ContentHash differs from the one
+// stored in stateFile by a previous run, storing the new hash if so. If stateFile doesn't
+// exist, the content counts as changed
+func (p *Pipe) HashChanged(stateFile string) (bool, error) {
+	sum, err := p.ContentHash()
+	if err != nil {
+		return false, err
+	}
+	previous, err := os.ReadFile(stateFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	if strings.TrimSpace(string(previous)) == sum {
+		return false, nil
+	}
+	return true, os.WriteFile(stateFile, []byte(sum+"\n"), 0o666)
+}
+
+// Head is like First, but sets the pipe's error if fewer than n lines were available
+func (p *Pipe) Head(n int) *Pipe {
+	return p.Pipe(xstd.Head(n))
+}
+
+// HexDecode reads the input as hexadecimal and outputs the decoded data
+func (p *Pipe) HexDecode() *Pipe {
+	return p.Pipe(xstd.HexDecode())
+}
+
+// HexEncode reads the input and outputs it encoded as hexadecimal
+func (p *Pipe) HexEncode() *Pipe {
+	return p.Pipe(xstd.HexEncode())
+}
+
+// HumanizeDuration reads a number of seconds from column col of each line and outputs the
+// line with the value rewritten as a duration like "1h2m3s"
+func (p *Pipe) HumanizeDuration(col int) *Pipe {
+	return p.Pipe(xstd.HumanizeDuration(col, time.Second))
+}
+
+// HumanizeDurationUnit is like HumanizeDuration, but reads a number of units, such as
+// time.Nanosecond, instead of seconds
+func (p *Pipe) HumanizeDurationUnit(col int, unit time.Duration) *Pipe {
+	return p.Pipe(xstd.HumanizeDuration(col, unit))
+}
+
+// Join reads all the lines and joins them into a single space-separated string
+func (p *Pipe) Join() *Pipe {
+	return p.Pipe(std.Join())
 }
 
 // JQ reads the input (presumed to be JSON), executes the query and outputs the result
@@ -153,9 +595,307 @@ func (p *Pipe) Get(url string) *Pipe {
 // 	return p.Pipe(gojq.JQ(query))
 // }
 
+// JSONColor reads the input as JSON and outputs it pretty-printed, highlighted with ANSI
+// colors if standard output is a terminal
+func (p *Pipe) JSONColor() *Pipe {
+	return p.Pipe(xstd.JSONColor())
+}
+
+// JSONColorWith is like JSONColor, but highlights the output if color is true, whether or
+// not standard output is a terminal
+func (p *Pipe) JSONColorWith(color bool) *Pipe {
+	return p.Pipe(xstd.JSONColorWith(color))
+}
+
+// JSONKeys reads the input as a JSON object and outputs its top-level keys in sorted order
+func (p *Pipe) JSONKeys() *Pipe {
+	return p.Pipe(xstd.JSONKeys())
+}
+
+// JSONKeysDeep reads the input as JSON and outputs the dotted path to every leaf value in sorted order
+func (p *Pipe) JSONKeysDeep() *Pipe {
+	return p.Pipe(xstd.JSONKeysDeep())
+}
+
+// JSONNumbersToStrings reads the input as JSON and outputs it with every number replaced by
+// a string, preserving its precision
+func (p *Pipe) JSONNumbersToStrings() *Pipe {
+	return p.Pipe(xstd.JSONNumbersToStrings())
+}
+
+// JSONPrettyStreaming reads the input as JSON and outputs it indented by indent, setting an
+// error instead if the input is larger than maxBuffer bytes
+func (p *Pipe) JSONPrettyStreaming(indent string, maxBuffer int64) *Pipe {
+	return p.Pipe(xstd.JSONPrettyStreaming(indent, maxBuffer))
+}
+
+// JSONStringsToNumbers reads the input as JSON and outputs it with every string that is a
+// valid JSON number replaced by that number
+func (p *Pipe) JSONStringsToNumbers() *Pipe {
+	return p.Pipe(xstd.JSONStringsToNumbers())
+}
+
+// JSONToLines reads the input as a JSON array of strings and outputs each element on its own line
+func (p *Pipe) JSONToLines() *Pipe {
+	return p.Pipe(xstd.JSONToLines())
+}
+
+// Last reads the input and outputs only the last n number of lines
+func (p *Pipe) Last(n int) *Pipe {
+	return p.Pipe(std.Last(n))
+}
+
+// LinesToJSON reads all the input and outputs the lines as a single JSON array of strings
+func (p *Pipe) LinesToJSON() *Pipe {
+	return p.Pipe(xstd.LinesToJSON())
+}
+
+// Map reads the input and returns a map from column keyCol of each line to column valCol, where
+// columns are 1-indexed and delimited by whitespace. Lines missing either column are skipped,
+// and if a key appears more than once, the last line with that key wins
+func (p *Pipe) Map(keyCol, valCol int) (map[string]string, error) {
+	lines, err := p.Slice()
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]string{}
+	for _, line := range lines {
+		columns := strings.Fields(line)
+		if keyCol < 1 || keyCol > len(columns) || valCol < 1 || valCol > len(columns) {
+			continue
+		}
+		m[columns[keyCol-1]] = columns[valCol-1]
+	}
+	return m, nil
+}
+
+// MarkdownCodeBlock reads all the input and outputs it wrapped in a fenced Markdown code
+// block with the language tag lang
+func (p *Pipe) MarkdownCodeBlock(lang string) *Pipe {
+	return p.Pipe(xstd.MarkdownCodeBlock(lang))
+}
+
+// Match reads the input and outputs lines that contain the string s
+func (p *Pipe) Match(s string) *Pipe {
+	return p.Pipe(std.Match(s))
+}
+
+// MatchRegexp reads the input and outputs lines that match the compiled regexp re
+func (p *Pipe) MatchRegexp(re *regexp.Regexp) *Pipe {
+	return p.Pipe(std.MatchRegexp(re))
+}
+
+// MD5Sum reads the input and outputs the hex-encoded MD5 hash
+func (p *Pipe) MD5Sum() (string, error) {
+	return p.Pipe(xstd.MD5Sum()).String()
+}
+
+// MD5Sums reads each line as a file path and outputs the hex-encoded MD5 hash of each file
+func (p *Pipe) MD5Sums() *Pipe {
+	return p.Pipe(xstd.MD5Sums())
+}
+
+// MatchContext reads the input and outputs lines that contain the string s, with before
+// and after lines of surrounding context
+func (p *Pipe) MatchContext(s string, before, after int) *Pipe {
+	return p.Pipe(xstd.MatchContext(s, before, after))
+}
+
+// MatchFold reads the input and outputs lines that contain the string s, ignoring case
+func (p *Pipe) MatchFold(s string) *Pipe {
+	return p.Pipe(xstd.MatchFold(s))
+}
+
+// MatchRegexpContext reads the input and outputs lines that match the compiled regexp re,
+// with before and after lines of surrounding context
+func (p *Pipe) MatchRegexpContext(re *regexp.Regexp, before, after int) *Pipe {
+	return p.Pipe(xstd.MatchRegexpContext(re, before, after))
+}
+
+// NearestLine reads the input and returns the line nearest to target by Levenshtein
+// distance, or the first such line if there's a tie
+func (p *Pipe) NearestLine(target string) (string, error) {
+	line, err := p.Pipe(xstd.NearestLine(target)).String()
+	return strings.TrimSuffix(line, "\n"), err
+}
+
+// NewSince reads the input and outputs the distinct lines not present in the snapshot file
+// snapshotPath, then replaces the snapshot with the current lines
+func (p *Pipe) NewSince(snapshotPath string) *Pipe {
+	return p.Pipe(xstd.NewSince(snapshotPath))
+}
+
+// NormalizeEmails reads each line as an email address and outputs it trimmed, with its
+// domain lowercased, skipping invalid addresses
+func (p *Pipe) NormalizeEmails() *Pipe {
+	return p.Pipe(xstd.NormalizeEmails())
+}
+
+// NumberLines reads the input and outputs each line prefixed with its line number, like cat -n
+func (p *Pipe) NumberLines() *Pipe {
+	return p.Pipe(xstd.NumberLines())
+}
+
+// NumberLinesFrom is like NumberLines, but numbers the lines starting at start
+func (p *Pipe) NumberLinesFrom(start int) *Pipe {
+	return p.Pipe(xstd.NumberLinesFrom(start))
+}
+
+// ParseDuration reads a duration like "1h2m3s" from column col of each line and outputs the
+// line with the value rewritten as a number of seconds
+func (p *Pipe) ParseDuration(col int) *Pipe {
+	return p.Pipe(xstd.ParseDuration(col, time.Second))
+}
+
+// ParseDurationUnit is like ParseDuration, but rewrites the value as a number of units, such
+// as time.Millisecond, instead of seconds
+func (p *Pipe) ParseDurationUnit(col int, unit time.Duration) *Pipe {
+	return p.Pipe(xstd.ParseDuration(col, unit))
+}
+
+// Pipe adds program to the pipeline. If the pipe has a context, the program
+// is aborted when the context is cancelled. The shortcuts inherited from
+// std.Pipeline add their programs to the embedded pipeline directly, bypassing
+// this method, which is why those shortcuts are declared again on Pipe
+func (p *Pipe) Pipe(program pipeline.Program) *Pipe {
+	if p.ctx != nil {
+		program = xstd.WithContext(p.ctx, program)
+	}
+	return p.Pipeline.Pipe(program)
+}
+
+// Patch reads the input as the request body, sends a PATCH request and outputs the response
+func (p *Pipe) Patch(url string) *Pipe {
+	return p.Pipe(xstd.Patch(url, p.doer()))
+}
+
+// PluginFilter reads the input and filters it through the Filter function exported by
+// the Go plugin at path
+func (p *Pipe) PluginFilter(path string) *Pipe {
+	return p.Pipe(xstd.PluginFilter(path))
+}
+
 // Get reads the input as the request body, sends a POST request and outputs the response
 func (p *Pipe) Post(url string) *Pipe {
-	return p.Pipe(std.Post(url, p.httpClient))
+	return p.Pipe(xstd.Post(url, p.doer()))
+}
+
+// PostJSON ignores its input, sends a POST request with v encoded as JSON as the request body
+// and outputs the response
+func (p *Pipe) PostJSON(url string, v any) *Pipe {
+	return p.Pipe(xstd.PostJSON(url, v, p.doer()))
+}
+
+// Put reads the input as the request body, sends a PUT request and outputs the response
+func (p *Pipe) Put(url string) *Pipe {
+	return p.Pipe(xstd.Put(url, p.doer()))
+}
+
+// Reject reads the input and outputs lines that do not contain the string s
+func (p *Pipe) Reject(s string) *Pipe {
+	return p.Pipe(std.Reject(s))
+}
+
+// RejectBinary reads each line as a file path and outputs only the paths of files that appear to be text
+func (p *Pipe) RejectBinary() *Pipe {
+	return p.Pipe(xstd.RejectBinary())
+}
+
+// RejectFold reads the input and outputs lines that do not contain the string s, ignoring case
+func (p *Pipe) RejectFold(s string) *Pipe {
+	return p.Pipe(xstd.RejectFold(s))
+}
+
+// RejectRegexp reads the input and outputs lines that do not match the compiled regexp re
+func (p *Pipe) RejectRegexp(re *regexp.Regexp) *Pipe {
+	return p.Pipe(std.RejectRegexp(re))
+}
+
+// RemovedSince reads the input and outputs the lines in the snapshot file snapshotPath that
+// are no longer present, then replaces the snapshot with the current lines
+func (p *Pipe) RemovedSince(snapshotPath string) *Pipe {
+	return p.Pipe(xstd.RemovedSince(snapshotPath))
+}
+
+// Replace reads the input and replaces all occurrences of the string search with the string replace
+func (p *Pipe) Replace(search, replace string) *Pipe {
+	return p.Pipe(std.Replace(search, replace))
+}
+
+// ReplaceN reads the input and replaces the first n occurrences of the string search in
+// each line with the string replace, or all of them if n is negative
+func (p *Pipe) ReplaceN(search, replace string, n int) *Pipe {
+	return p.Pipe(xstd.ReplaceN(search, replace, n))
+}
+
+// ReplaceRegexp reads the input and replaces all matches of the compiled regexp re with the string replace
+func (p *Pipe) ReplaceRegexp(re *regexp.Regexp, replace string) *Pipe {
+	return p.Pipe(std.ReplaceRegexp(re, replace))
+}
+
+// ReplaceRegexpFunc reads the input and replaces all matches of the compiled regexp re with
+// the result of calling repl with the full text of each match
+func (p *Pipe) ReplaceRegexpFunc(re *regexp.Regexp, repl func(string) string) *Pipe {
+	return p.Pipe(xstd.ReplaceRegexpFunc(re, repl))
+}
+
+// RequireLineCount reads the input and outputs it unchanged, setting an error if the number
+// of lines is less than min or, if max is positive, greater than max
+func (p *Pipe) RequireLineCount(min, max int) *Pipe {
+	return p.Pipe(xstd.RequireLineCount(min, max))
+}
+
+// RequireNonEmpty reads the input and outputs it unchanged, setting an error with the
+// message msg if the input is empty
+func (p *Pipe) RequireNonEmpty(msg string) *Pipe {
+	return p.Pipe(xstd.RequireNonEmpty(msg))
+}
+
+// ResolveURL reads each line as a URL and outputs it resolved against the URL base
+func (p *Pipe) ResolveURL(base string) *Pipe {
+	return p.Pipe(xstd.ResolveURL(base))
+}
+
+// Reverse reads all the input and outputs the lines in reverse order
+func (p *Pipe) Reverse() *Pipe {
+	return p.Pipe(xstd.Reverse())
+}
+
+// RunningMinMax reads numeric values from column col of each line and outputs the line
+// followed by the minimum and maximum values seen so far
+func (p *Pipe) RunningMinMax(col int) *Pipe {
+	return p.Pipe(xstd.RunningMinMax(col))
+}
+
+// Sample reads the input and outputs up to n randomly chosen lines, holding only n lines in memory
+func (p *Pipe) Sample(n int) *Pipe {
+	return p.Pipe(xstd.Sample(n))
+}
+
+// SampleSeed is like Sample, but chooses the lines reproducibly using the given random seed
+func (p *Pipe) SampleSeed(n int, seed int64) *Pipe {
+	return p.Pipe(xstd.SampleSeed(n, seed))
+}
+
+// Scanner reads the input into a scanner, calls the function filter on each line and outputs the result
+func (p *Pipe) Scanner(filter func(string, io.Writer)) *Pipe {
+	return p.Pipe(std.Scanner(filter))
+}
+
+// SelectColumns reads the input as a table with a header line and outputs only the columns
+// named by headers, in that order
+func (p *Pipe) SelectColumns(headers ...string) *Pipe {
+	return p.Pipe(xstd.SelectColumns(headers...))
+}
+
+// SHA1Sum reads the input and outputs the hex-encoded SHA-1 hash
+func (p *Pipe) SHA1Sum() (string, error) {
+	return p.Pipe(xstd.SHA1Sum()).String()
+}
+
+// SHA1Sums reads each line as a file path and outputs the hex-encoded SHA-1 hash of each file
+func (p *Pipe) SHA1Sums() *Pipe {
+	return p.Pipe(xstd.SHA1Sums())
 }
 
 // SHA256Sum reads the input and outputs the hex-encoded SHA-256 hash
@@ -163,6 +903,81 @@ func (p *Pipe) SHA256Sum() (string, error) {
 	return p.Pipe(std.SHA256Sum()).String()
 }
 
+// SHA256Sums reads the input and outputs the hex-encoded SHA-256 hash of each line
+func (p *Pipe) SHA256Sums() *Pipe {
+	return p.Pipe(std.SHA256Sums())
+}
+
+// SHA512Sum reads the input and outputs the hex-encoded SHA-512 hash
+func (p *Pipe) SHA512Sum() (string, error) {
+	return p.Pipe(xstd.SHA512Sum()).String()
+}
+
+// SHA512Sums reads each line as a file path and outputs the hex-encoded SHA-512 hash of each file
+func (p *Pipe) SHA512Sums() *Pipe {
+	return p.Pipe(xstd.SHA512Sums())
+}
+
+// Shuffle reads all the input and outputs the lines in a random order
+func (p *Pipe) Shuffle() *Pipe {
+	return p.Pipe(xstd.Shuffle())
+}
+
+// ShuffleSeed is like Shuffle, but chooses the order reproducibly using the given random seed
+func (p *Pipe) ShuffleSeed(seed int64) *Pipe {
+	return p.Pipe(xstd.ShuffleSeed(seed))
+}
+
+// Skip reads the input and outputs all but the first n lines
+func (p *Pipe) Skip(n int) *Pipe {
+	return p.Pipe(xstd.Skip(n))
+}
+
+// SkipLast reads the input and outputs all but the last n lines
+func (p *Pipe) SkipLast(n int) *Pipe {
+	return p.Pipe(xstd.SkipLast(n))
+}
+
+// Sort reads all the input and outputs the lines in lexical order
+func (p *Pipe) Sort() *Pipe {
+	return p.Pipe(xstd.Sort())
+}
+
+// SortNumeric reads all the input and outputs the lines in ascending numerical order
+func (p *Pipe) SortNumeric() *Pipe {
+	return p.Pipe(xstd.SortNumeric())
+}
+
+// SortReverse reads all the input and outputs the lines in descending numerical order
+func (p *Pipe) SortReverse() *Pipe {
+	return p.Pipe(xstd.SortReverse())
+}
+
+// SplitPipe reads the input, splits it into chunks separated by delimiter, runs each chunk
+// through the sub-pipeline built by stage, and outputs the concatenated results
+func (p *Pipe) SplitPipe(delimiter string, stage func(*Pipe) *Pipe) *Pipe {
+	newPipe := p.subPipes()
+	return p.Pipe(xstd.SplitEach(delimiter, func(chunk io.Reader, w io.Writer) error {
+		sub := stage(newPipe().WithReader(chunk))
+		if _, err := io.Copy(w, sub); err != nil {
+			return err
+		}
+		return sub.Error()
+	}))
+}
+
+// SubtractColumn reads the input and outputs only the lines whose column col isn't among the
+// keys listed one per line in the file path
+func (p *Pipe) SubtractColumn(col int, path string) *Pipe {
+	return p.Pipe(xstd.SubtractColumn(col, path))
+}
+
+// Template reads all the input and outputs the result of executing the Go template tmpl,
+// where {{.}} is the input and {{.Lines}} its lines
+func (p *Pipe) Template(tmpl string) *Pipe {
+	return p.Pipe(xstd.Template(tmpl))
+}
+
 // Tee reads the input and copies it to each of the supplied writers, like Unix tee(1)
 func (p *Pipe) Tee(writers ...io.Writer) *Pipe {
 	if len(writers) == 0 {
@@ -171,20 +986,239 @@ func (p *Pipe) Tee(writers ...io.Writer) *Pipe {
 	return p.Pipe(std.Tee(writers...))
 }
 
+// TeeAppendFile reads the input and appends it to each of the files paths, creating them
+// if necessary, as well as outputting it
+func (p *Pipe) TeeAppendFile(paths ...string) *Pipe {
+	return p.Pipe(xstd.TeeAppendFile(paths...))
+}
+
+// TeeFile reads the input and writes it to each of the files paths, truncating them if
+// they exist, as well as outputting it
+func (p *Pipe) TeeFile(paths ...string) *Pipe {
+	return p.Pipe(xstd.TeeFile(paths...))
+}
+
+// TeeLineCount reads the input and outputs it unchanged, calling fn with the cumulative
+// number of lines every 1000 lines and on completion
+func (p *Pipe) TeeLineCount(fn func(n int)) *Pipe {
+	return p.Pipe(xstd.TeeLineCount(1000, fn))
+}
+
+// ToMarkdownTable reads whitespace-delimited rows and outputs them as a Markdown table,
+// using the first row as the header
+func (p *Pipe) ToMarkdownTable() *Pipe {
+	return p.Pipe(xstd.ToMarkdownTable())
+}
+
+// ToMarkdownTableDelim is like ToMarkdownTable, but columns are delimited by delim
+func (p *Pipe) ToMarkdownTableDelim(delim string) *Pipe {
+	return p.Pipe(xstd.ToMarkdownTableDelim(delim))
+}
+
+// TrimPrefix reads the input and outputs each line with the leading prefix removed
+func (p *Pipe) TrimPrefix(prefix string) *Pipe {
+	return p.Pipe(xstd.TrimPrefix(prefix))
+}
+
+// TrimSpace reads the input and outputs each line with leading and trailing white space removed
+func (p *Pipe) TrimSpace() *Pipe {
+	return p.Pipe(xstd.TrimSpace())
+}
+
+// TrimSuffix reads the input and outputs each line with the trailing suffix removed
+func (p *Pipe) TrimSuffix(suffix string) *Pipe {
+	return p.Pipe(xstd.TrimSuffix(suffix))
+}
+
+// Truncate reads the input and outputs at most the first n bytes
+func (p *Pipe) Truncate(n int64) *Pipe {
+	return p.Pipe(xstd.Truncate(n))
+}
+
+// Uniq reads the input and outputs each line that differs from the line before it
+func (p *Pipe) Uniq() *Pipe {
+	return p.Pipe(xstd.Uniq())
+}
+
+// UniqCount reads the input and outputs each run of identical adjacent lines once,
+// prefixed with the number of lines in the run
+func (p *Pipe) UniqCount() *Pipe {
+	return p.Pipe(xstd.UniqCount())
+}
+
+// WhereColumn reads the input as a table with a header line and outputs the header and the
+// lines whose column named header satisfies predicate
+func (p *Pipe) WhereColumn(header string, predicate func(string) bool) *Pipe {
+	return p.Pipe(xstd.WhereColumn(header, predicate))
+}
+
+// WithOffsets reads the input and outputs each line prefixed with its starting byte offset
+// and a tab
+func (p *Pipe) WithOffsets() *Pipe {
+	return p.Pipe(xstd.WithOffsets())
+}
+
+// WordFreq reads the input and outputs each distinct word prefixed with its frequency count,
+// in descending numerical order
+func (p *Pipe) WordFreq() *Pipe {
+	return p.Pipe(xstd.WordFreq(false, false))
+}
+
+// WordFreqWith is like WordFreq, but optionally ignores case (fold) and removes leading and
+// trailing punctuation from words (trimPunct)
+func (p *Pipe) WordFreqWith(fold, trimPunct bool) *Pipe {
+	return p.Pipe(xstd.WordFreq(fold, trimPunct))
+}
+
+// WrapOn reads the input and outputs it with lines longer than maxLen broken after the last
+// sep that fits, so that words or fields aren't split
+func (p *Pipe) WrapOn(maxLen int, sep string) *Pipe {
+	return p.Pipe(xstd.WrapOn(maxLen, sep))
+}
+
 // WriteFile reads the input and writes it to the file path, truncating it if it exists,
 // and outputs the number of bytes successfully written
 func (p *Pipe) WriteFile(path string) (int64, error) {
 	return p.Pipe(std.WriteFile(path)).Int64()
 }
 
+// WriteFileAll is like WriteFile, but first creates the file's parent directories if
+// necessary, like mkdir -p
+func (p *Pipe) WriteFileAll(path string) (int64, error) {
+	return p.Pipe(xstd.WriteFileAll(path)).Int64()
+}
+
+// WriteFileBOM reads the input and writes it to the file path prefixed with a UTF-8 byte
+// order mark, and outputs the number of bytes successfully written, including the BOM
+func (p *Pipe) WriteFileBOM(path string) (int64, error) {
+	return p.Pipe(xstd.WriteFileBOM(path)).Int64()
+}
+
+// WriteFileMode is like WriteFile, but creates the file with the permissions perm, or
+// changes the permissions of an existing file to perm
+func (p *Pipe) WriteFileMode(path string, perm os.FileMode) (int64, error) {
+	return p.Pipe(xstd.WriteFileMode(path, perm)).Int64()
+}
+
 // With* functions:
 
+// WithContext sets the context ctx for subsequent stages. When ctx is cancelled, these
+// stages are aborted, including running commands and HTTP requests, and the pipe's
+// error is set to ctx.Err()
+func (p *Pipe) WithContext(ctx context.Context) *Pipe {
+	p.ctx = ctx
+	return p
+}
+
+// WithDir sets the working directory of subsequently executed commands to path. It applies
+// to the Exec, ExecForEach, ExecForEachParallelFunc and ExecTagged stages, but not to stages
+// from the shell module piped in with Pipe, which build their own commands. If path isn't an
+// existing directory, the pipe's error status is set and the working directory is unchanged
+func (p *Pipe) WithDir(path string) *Pipe {
+	info, err := os.Stat(path)
+	if err == nil && !info.IsDir() {
+		err = fmt.Errorf("%s: not a directory", path)
+	}
+	if err != nil {
+		p.SetError(err)
+		return p
+	}
+	p.dir = path
+	return p
+}
+
+// WithEnv sets the environment variables in env for subsequently executed commands, in
+// addition to those inherited from the current process and set by previous calls, which
+// they override. It applies to the Exec, ExecForEach, ExecForEachParallelFunc and
+// ExecTagged stages, but not to stages from the shell module piped in with Pipe, which
+// build their own commands
+func (p *Pipe) WithEnv(env map[string]string) *Pipe {
+	if len(env) == 0 {
+		return p
+	}
+	merged := make(map[string]string, len(p.env)+len(env))
+	for key, value := range p.env {
+		merged[key] = value
+	}
+	for key, value := range env {
+		merged[key] = value
+	}
+	p.env = merged
+	return p
+}
+
+// WithEnvFile sets the environment variables in the dotenv-style file path for subsequently
+// executed commands, as for WithEnv. Each line of the file has the form KEY=VALUE, optionally
+// preceded by "export". Values may be enclosed in single quotes, taken literally, or double
+// quotes, in which Go escape sequences such as \n are interpreted. Blank lines, lines
+// starting with # and, outside quotes, text from " #" onwards are ignored. If the file
+// can't be read or parsed, the pipe's error status is set
+func (p *Pipe) WithEnvFile(path string) *Pipe {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		p.SetError(err)
+		return p
+	}
+	env, err := parseEnv(string(data))
+	if err != nil {
+		p.SetError(fmt.Errorf("%s: %w", path, err))
+		return p
+	}
+	return p.WithEnv(env)
+}
+
+// WithHeader adds the header key with value to HTTP requests made by subsequent Do, Get
+// and Post stages. Adding the same key again adds another value for it
+func (p *Pipe) WithHeader(key, value string) *Pipe {
+	if p.header == nil {
+		p.header = http.Header{}
+	}
+	p.header.Add(key, value)
+	return p
+}
+
+// WithHeaders adds the headers in h to HTTP requests made by subsequent Do, Get and Post
+// stages, in addition to any headers already added
+func (p *Pipe) WithHeaders(h http.Header) *Pipe {
+	for key, values := range h {
+		for _, value := range values {
+			p.WithHeader(key, value)
+		}
+	}
+	return p
+}
+
 // WithHTTPClient sets the HTTP client c for use with subsequent requests
 func (p *Pipe) WithHTTPClient(c *http.Client) *Pipe {
 	p.httpClient = c
 	return p
 }
 
+// WithProgress makes Run and Stdout call fn with the number of bytes written so far after
+// each further 64KiB is copied to the pipe's standard output, and once more with the total
+// when the copy is complete. fn is always called from the goroutine calling Run or Stdout
+func (p *Pipe) WithProgress(fn func(bytesWritten int64)) *Pipe {
+	p.progress = fn
+	return p
+}
+
+// WithRequestRate limits the HTTP requests made by subsequent Do, Get and Post stages
+// to perSecond requests per second in total
+func (p *Pipe) WithRequestRate(perSecond float64) *Pipe {
+	p.requestLimiter = xstd.NewRateLimiter(perSecond)
+	return p
+}
+
+// WithRetry makes subsequent Do, Get and Post stages try each HTTP request up to attempts
+// times in total if it fails with a network error or a 5xx status, waiting for backoff
+// before the first retry and doubling the wait each time. The request body is buffered
+// so that it can be sent again
+func (p *Pipe) WithRetry(attempts int, backoff time.Duration) *Pipe {
+	p.retryAttempts = attempts
+	p.retryBackoff = backoff
+	return p
+}
+
 // WithStdout sets the pipe's standard output to the writer w
 func (p *Pipe) WithStdout(w io.Writer) *Pipe {
 	p.stdout = w
@@ -198,6 +1232,181 @@ func (p *Pipe) WithStderr(w io.Writer) *Pipe {
 	return p
 }
 
+// WithTimeout aborts each HTTP request made by subsequent Do, Get and Post stages that has
+// not completed within d, including streaming the response body. The pipe's error then
+// matches context.DeadlineExceeded
+func (p *Pipe) WithTimeout(d time.Duration) *Pipe {
+	p.requestTimeout = d
+	return p
+}
+
+// doer returns the configured HTTP client, wrapped with any request options
+func (p *Pipe) doer() xstd.Doer {
+	var c xstd.Doer = p.httpClient
+	if p.requestTimeout > 0 {
+		c = xstd.Timeout(c, p.requestTimeout)
+	}
+	if p.requestLimiter != nil {
+		c = xstd.RateLimit(c, p.requestLimiter)
+	}
+	if len(p.header) > 0 {
+		c = xstd.Header(c, p.header.Clone())
+	}
+	if p.retryAttempts > 1 {
+		c = xstd.Retry(c, p.retryAttempts, p.retryBackoff)
+	}
+	if p.ctx != nil {
+		c = xstd.RequestContext(c, p.ctx)
+	}
+	return c
+}
+
+// command returns the command with name and arguments, configured with the pipe's options
+func (p *Pipe) command(name string, arg ...string) *exec.Cmd {
+	return p.commander()(name, arg...)
+}
+
+// commander returns a function creating commands configured with the pipe's current options
+func (p *Pipe) commander() func(name string, arg ...string) *exec.Cmd {
+	ctx, dir := p.ctx, p.dir
+	var env []string
+	if len(p.env) > 0 {
+		env = mergeEnv(os.Environ(), p.env)
+	}
+	return func(name string, arg ...string) *exec.Cmd {
+		var cmd *exec.Cmd
+		if ctx != nil {
+			cmd = exec.CommandContext(ctx, name, arg...)
+		} else {
+			cmd = exec.Command(name, arg...)
+		}
+		cmd.Env = env
+		cmd.Dir = dir
+		return cmd
+	}
+}
+
+// subPipes returns a function creating new pipes configured with the pipe's current options
+func (p *Pipe) subPipes() func() *Pipe {
+	ctx, env, dir := p.ctx, p.env, p.dir
+	httpClient, header := p.httpClient, p.header.Clone()
+	limiter, timeout := p.requestLimiter, p.requestTimeout
+	attempts, backoff := p.retryAttempts, p.retryBackoff
+	return func() *Pipe {
+		sub := NewPipe()
+		sub.ctx, sub.env, sub.dir = ctx, env, dir
+		sub.httpClient, sub.header = httpClient, header.Clone()
+		sub.requestLimiter, sub.requestTimeout = limiter, timeout
+		sub.retryAttempts, sub.retryBackoff = attempts, backoff
+		return sub
+	}
+}
+
+// progressInterval is the number of bytes copied between calls to a WithProgress callback
+const progressInterval = 64 * 1024
+
+// progressReader reads from r, calling fn with the total number of bytes read whenever the
+// total reaches next, which then advances to the following multiple of progressInterval
+type progressReader struct {
+	r     io.Reader
+	fn    func(int64)
+	total int64
+	next  int64
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.total += int64(n)
+	if r.total >= r.next {
+		r.fn(r.total)
+		r.next = (r.total/progressInterval + 1) * progressInterval
+	}
+	return n, err
+}
+
+// parseEnv parses the contents of a dotenv-style file, as described for WithEnvFile
+func parseEnv(data string) (map[string]string, error) {
+	env := map[string]string{}
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: want KEY=VALUE, got %q", i+1, line)
+		}
+		value, err := parseEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		env[key] = value
+	}
+	return env, nil
+}
+
+// parseEnvValue returns the value of a dotenv variable, removing quotes and comments
+func parseEnvValue(raw string) (string, error) {
+	var value string
+	var end int
+	switch {
+	case strings.HasPrefix(raw, "'"):
+		end = strings.Index(raw[1:], "'") + 1
+		if end == 0 {
+			return "", fmt.Errorf("unterminated quoted value %s", raw)
+		}
+		value = raw[1:end]
+	case strings.HasPrefix(raw, `"`):
+		for end = 1; end < len(raw) && raw[end] != '"'; end++ {
+			if raw[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(raw) {
+			return "", fmt.Errorf("unterminated quoted value %s", raw)
+		}
+		var err error
+		if value, err = strconv.Unquote(raw[:end+1]); err != nil {
+			return "", fmt.Errorf("invalid quoted value %s", raw[:end+1])
+		}
+	default:
+		if i := strings.Index(raw, " #"); i >= 0 {
+			raw = raw[:i]
+		}
+		return strings.TrimSpace(raw), nil
+	}
+	if rest := strings.TrimSpace(raw[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected %q after quoted value", rest)
+	}
+	return value, nil
+}
+
+// mergeEnv returns the "key=value" entries of environ, with the variables in env
+// replacing or added to them
+func mergeEnv(environ []string, env map[string]string) []string {
+	merged := make([]string, 0, len(environ)+len(env))
+	for _, kv := range environ {
+		key := kv
+		if i := strings.Index(kv, "="); i >= 0 {
+			key = kv[:i]
+		}
+		if _, ok := env[key]; !ok {
+			merged = append(merged, kv)
+		}
+	}
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		merged = append(merged, key+"="+env[key])
+	}
+	return merged
+}
+
 func NewReadAutoCloser(r io.Reader) io.Reader {
 	return pipeline.NewReadOnlyPipe(r)
 }
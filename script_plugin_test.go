@@ -0,0 +1,38 @@
+//go:build (linux || darwin || freebsd) && cgo
+
+package script_test
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/bartdeboer/script/v2"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPluginFilter_FiltersInputThroughPluginFilterFunc(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "upper.so")
+	out, err := exec.Command("go", "build", "-buildmode=plugin", "-o", path, "./testdata/plugin/upper").CombinedOutput()
+	if err != nil {
+		t.Skipf("can't build plugin: %v\n%s", err, out)
+	}
+	want := "HELLO, WORLD\n"
+	got, err := script.Echo("Hello, world\n").PluginFilter(path).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestPluginFilter_ErrorsGivenNonexistentPlugin(t *testing.T) {
+	t.Parallel()
+	p := script.Echo("hello").PluginFilter("doesntexist.so")
+	p.Wait()
+	if p.Error() == nil {
+		t.Error("want error loading non-existent plugin")
+	}
+}
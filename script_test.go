@@ -3,20 +3,30 @@ package script_test
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"testing/iotest"
+	"time"
 
+	"github.com/bartdeboer/pipeline/std"
 	"github.com/bartdeboer/script/v2"
+	xstd "github.com/bartdeboer/script/v2/std"
 	"github.com/google/go-cmp/cmp"
 	"github.com/rogpeppe/go-internal/testscript"
 )
@@ -41,6 +51,51 @@ func TestScript(t *testing.T) {
 	})
 }
 
+func TestBase64EncodeEncodesInputAsBase64(t *testing.T) {
+	t.Parallel()
+	want := "aGVsbG8sIHdvcmxkPz4+"
+	got, err := script.Echo("hello, world?>>").Base64Encode().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestBase64EncodeWithUsesSuppliedEncoding(t *testing.T) {
+	t.Parallel()
+	want := "aGVsbG8sIHdvcmxkPz4-"
+	got, err := script.Echo("hello, world?>>").Base64EncodeWith(base64.URLEncoding).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestBase64DecodeRoundTripsBinaryData(t *testing.T) {
+	t.Parallel()
+	want := []byte{0, 1, 2, 253, 254, 255, '\n'}
+	got, err := script.NewPipe().WithReader(bytes.NewReader(want)).Base64Encode().Base64Decode().Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestBase64DecodeSetsErrorGivenInvalidInput(t *testing.T) {
+	t.Parallel()
+	p := script.Echo("not*base64").Base64Decode()
+	p.Wait()
+	if p.Error() == nil {
+		t.Error("want error given invalid base64 input")
+	}
+}
+
 func TestBasenameRemovesLeadingPathComponentsFromInputLines(t *testing.T) {
 	t.Parallel()
 	tcs := []struct {
@@ -70,6 +125,134 @@ func TestBasenameRemovesLeadingPathComponentsFromInputLines(t *testing.T) {
 	}
 }
 
+func TestAggregateBySumsValueColumnGroupedByKeyColumn(t *testing.T) {
+	t.Parallel()
+	input := strings.Join([]string{
+		"web GET 12",
+		"db SELECT 3.5",
+		"web POST 8",
+		"cache GET n/a",
+		"db UPDATE 1.5",
+		"",
+	}, "\n")
+	want := "cache 0\ndb 5\nweb 20\n"
+	got, err := script.Echo(input).AggregateBy(1, 3, "sum").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestAggregateBySetsErrorGivenUnknownOperation(t *testing.T) {
+	t.Parallel()
+	p := script.Echo("a 1\n").AggregateBy(1, 2, "median")
+	p.Wait()
+	if p.Error() == nil {
+		t.Error("want error given unknown operation")
+	}
+}
+
+func TestBucketizeCountsColumnValuesInFixedWidthBuckets(t *testing.T) {
+	t.Parallel()
+	input := strings.Join([]string{
+		"GET /a 12",
+		"GET /b 7",
+		"GET /c 250",
+		"GET /d 99.5",
+		"GET /e 100",
+		"GET /f -3",
+		"GET /g n/a",
+		"short",
+		"GET /h 0",
+	}, "\n")
+	want := "-100-0: 1\n0-100: 4\n100-200: 1\n200-300: 1\n"
+	got, err := script.Echo(input).Bucketize(3, 100).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestBucketizeIgnoresNaNAndInfiniteValues(t *testing.T) {
+	t.Parallel()
+	want := "0-10: 2\n"
+	got, err := script.Echo("1\nNaN\n+Inf\n-Inf\n5\n").Bucketize(1, 10).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestBucketizeSetsErrorGivenNonPositiveBucketSize(t *testing.T) {
+	t.Parallel()
+	p := script.Echo("1\n2\n").Bucketize(1, 0)
+	p.Wait()
+	if p.Error() == nil {
+		t.Error("want error given zero bucket size")
+	}
+}
+
+func TestByteRangeOutputsLengthBytesAfterOffset(t *testing.T) {
+	t.Parallel()
+	input := "0123456789"
+	tcs := []struct {
+		offset, length int64
+		want           string
+	}{
+		{offset: 2, length: 3, want: "234"},
+		{offset: 0, length: 4, want: "0123"},
+		{offset: 7, length: -1, want: "789"},
+		{offset: 8, length: 10, want: "89"},
+		{offset: 20, length: 5, want: ""},
+		{offset: 3, length: 0, want: ""},
+	}
+	for _, tc := range tcs {
+		got, err := script.Echo(input).ByteRange(tc.offset, tc.length).String()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.want != got {
+			t.Errorf("ByteRange(%d, %d): %s", tc.offset, tc.length, cmp.Diff(tc.want, got))
+		}
+	}
+}
+
+func TestCanonicalJSONProducesIdenticalOutputForEquivalentDocuments(t *testing.T) {
+	t.Parallel()
+	a := `{"b": [1, 2.50, {"y": true, "x": null}], "a": "<tag>"}`
+	b := "{\n  \"a\": \"<tag>\",\n  \"b\": [\n    1,\n    2.50,\n    {\"x\": null, \"y\": true}\n  ]\n}\n"
+	want := `{"a":"<tag>","b":[1,2.50,{"x":null,"y":true}]}` + "\n"
+	gotA, err := script.Echo(a).CanonicalJSON().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotB, err := script.Echo(b).CanonicalJSON().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != gotA {
+		t.Error(cmp.Diff(want, gotA))
+	}
+	if gotA != gotB {
+		t.Error(cmp.Diff(gotA, gotB))
+	}
+}
+
+func TestCanonicalJSONSetsErrorGivenInvalidJSON(t *testing.T) {
+	t.Parallel()
+	p := script.Echo(`{"a":`).CanonicalJSON()
+	p.Wait()
+	if p.Error() == nil {
+		t.Error("want error given invalid JSON")
+	}
+}
+
 func TestColumnSelects(t *testing.T) {
 	t.Parallel()
 	input := []string{
@@ -193,184 +376,194 @@ func TestColumnSelects(t *testing.T) {
 	}
 }
 
-func TestConcatOutputsContentsOfSpecifiedFilesInOrder(t *testing.T) {
-	t.Parallel()
-	want := "This is the first line in the file.\nHello, world.\nThis is another line in the file.\nhello world"
-	got, err := script.Echo("testdata/test.txt\ntestdata/doesntexist.txt\ntestdata/hello.txt").Concat().String()
-	if err != nil {
-		t.Fatal(err)
-	}
-	if !cmp.Equal(want, got) {
-		t.Error(cmp.Diff(want, got))
-	}
-}
-
-func TestDirname_RemovesFilenameComponentFromInputLines(t *testing.T) {
+func TestColumnDelimSelectsColumnPreservingEmptyFields(t *testing.T) {
 	t.Parallel()
+	input := "a,b,c\nd,,f\ng\n,h,i,j\n"
 	tcs := []struct {
-		path string
+		col  int
 		want string
 	}{
-		{"/", "/\n"},
-		{"./", ".\n"},
-		{"\n", ".\n"},
-		{"/root", "/\n"},
-		{"/tmp/example.php", "/tmp\n"},
-		{"/var/tmp/example.php", "/var/tmp\n"},
-		{"/var/tmp", "/var\n"},
-		{"/var/tmp/", "/var\n"},
-		{"src/filters", "./src\n"},
-		{"src/filters/", "./src\n"},
-		{"/tmp/script-21345.txt\n/tmp/script-5371253.txt", "/tmp\n/tmp\n"},
-		{"C:/Program Files/PHP", "C:/Program Files\n"},
-		{"C:/Program Files/PHP/", "C:/Program Files\n"},
+		{0, ""},
+		{1, "a\nd\ng\n\n"},
+		{2, "b\n\nh\n"},
+		{4, "j\n"},
+		{5, ""},
 	}
 	for _, tc := range tcs {
-		// Expect results to use this platform's path separator
-		want := filepath.Clean(tc.want)
-		got, err := script.Echo(tc.path).Dirname().String()
+		got, err := script.Echo(input).ColumnDelim(tc.col, ",").String()
 		if err != nil {
 			t.Fatal(err)
 		}
-		if want != got {
-			t.Errorf("%q: want %q, got %q", tc.path, want, got)
+		if tc.want != got {
+			t.Errorf("col %d: %s", tc.col, cmp.Diff(tc.want, got))
 		}
 	}
 }
 
-func TestDoPerformsSuppliedHTTPRequest(t *testing.T) {
+func TestConcatOutputsContentsOfSpecifiedFilesInOrder(t *testing.T) {
 	t.Parallel()
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintln(w, "some data")
-	}))
-	defer ts.Close()
-	req, err := http.NewRequest(http.MethodGet, ts.URL, http.NoBody)
+	want := "This is the first line in the file.\nHello, world.\nThis is another line in the file.\nhello world"
+	got, err := script.Echo("testdata/test.txt\ntestdata/doesntexist.txt\ntestdata/hello.txt").Concat().String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	want := "some data\n"
-	got, err := script.Do(req).String()
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
 	if !cmp.Equal(want, got) {
 		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestEachLine_FiltersInputThroughSuppliedFunction(t *testing.T) {
+func TestCSVOutputsSelectedFieldsHonouringQuotes(t *testing.T) {
 	t.Parallel()
-	want := "Hello world\nGoodbye world\n"
-	got, err := script.Echo("Hello\nGoodbye").
-		EachLine(func(line string, out *strings.Builder) {
-			out.WriteString(line + " world\n")
-		}).String()
+	input := "name,address,age\n\"Smith, John\",\"1 \"\"Main\"\" St\",42\nshort\n"
+	want := "age\tname\n42\tSmith, John\n\tshort\n"
+	got, err := script.Echo(input).CSV(3, 1).String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if got != want {
-		t.Errorf("want %q, got %q", want, got)
+	if want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestEachLine_HandlesLongLines(t *testing.T) {
+func TestCSVDelimUsesSuppliedDelimiter(t *testing.T) {
 	t.Parallel()
-	var got int
-	_, err := script.Echo(longLine).
-		EachLine(func(line string, out *strings.Builder) {
-			got++
-		}).String()
+	got, err := script.Echo("a;\"b;c\";d\n").CSVDelim(';').String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	want := 2
-	if want != got {
-		t.Errorf("want %d lines counted, got %d", want, got)
+	if want := "a\tb;c\td\n"; want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestEchoProducesSuppliedString(t *testing.T) {
+func TestCSVSetsErrorGivenMalformedInputOrInvalidField(t *testing.T) {
 	t.Parallel()
-	want := "Hello, world."
-	p := script.Echo(want)
-	got, err := p.String()
+	_, err := script.Echo("a,\"b\n").CSV(1).String()
+	if err == nil {
+		t.Error("want error given unterminated quote")
+	}
+	_, err = script.Echo("a,b\n").CSV(0).String()
+	if err == nil {
+		t.Error("want error given field 0")
+	}
+}
+
+func TestCSVToJSONOutputsObjectsKeyedByHeaders(t *testing.T) {
+	t.Parallel()
+	input := "name,note\n\"Smith, John\",<b>\nalice\nbob,x,extra\n"
+	want := strings.Join([]string{
+		`{"name":"Smith, John","note":"<b>"}`,
+		`{"name":"alice"}`,
+		`{"name":"bob","note":"x","col3":"extra"}`,
+	}, "\n") + "\n"
+	got, err := script.Echo(input).CSVToJSON(true).String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if got != want {
-		t.Errorf("want %q, got %q", want, got)
+	if want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestEchoReplacesInputWithSuppliedStringWhenUsedAsFilter(t *testing.T) {
+func TestCSVToJSONOutputsArraysWithoutHeaders(t *testing.T) {
 	t.Parallel()
-	want := "Hello, world."
-	p := script.Echo("bogus").Echo(want)
-	got, err := p.String()
+	want := "[\"a\",\"b\"]\n[\"c\"]\n"
+	got, err := script.Echo("a,b\nc\n").CSVToJSON(false).String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if got != want {
-		t.Errorf("want %q, got %q", want, got)
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+	_, err = script.Echo("a,\"b\n").CSVToJSON(false).String()
+	if err == nil {
+		t.Error("want error given malformed CSV")
 	}
 }
 
-func TestExecForEach_ErrorsOnInvalidTemplateSyntax(t *testing.T) {
+func TestCutSelectsFieldRanges(t *testing.T) {
 	t.Parallel()
-	p := script.Echo("a\nb\nc\n").ExecForEach(func(line string) (string, []string) { return "", nil })
-	p.Wait()
-	if p.Error() == nil {
-		t.Error("want error with invalid template syntax")
+	input := "1\t2\t3\t4\t5\t6\t7\t8\na\tb\tc\nonly\n"
+	tcs := []struct {
+		ranges string
+		want   string
+	}{
+		{"2,4", "2\t4\nb\n"},
+		{"1,3-5,7-", "1\t3\t4\t5\t7\t8\na\tc\nonly\n"},
+		{"-2", "1\t2\na\tb\nonly\n"},
+		{"6-", "6\t7\t8\n"},
+		{"4,2", "2\t4\nb\n"},
+	}
+	for _, tc := range tcs {
+		got, err := script.Echo(input).Cut(tc.ranges, "\t").String()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.want != got {
+			t.Errorf("%q: %s", tc.ranges, cmp.Diff(tc.want, got))
+		}
 	}
 }
 
-func TestExecForEach_ErrorsOnUnbalancedQuotes(t *testing.T) {
+func TestCutSetsErrorGivenInvalidRanges(t *testing.T) {
 	t.Parallel()
-	p := script.Echo("a\nb\nc\n").ExecForEach(func(line string) (string, []string) { return "echo", []string{line} })
-	p.Wait()
-	if p.Error() == nil {
-		t.Error("want error with unbalanced quotes in command line")
+	for _, ranges := range []string{"", "0", "a", "3-1", "-", "1,,2"} {
+		p := script.Echo("a b c\n").Cut(ranges, " ")
+		p.Wait()
+		if p.Error() == nil {
+			t.Errorf("%q: want error given invalid ranges", ranges)
+		}
 	}
 }
 
-func TestExecForEach_SendsStderrOutputToPipeStderr(t *testing.T) {
+func TestDataURIEncodesInputAsBase64DataURI(t *testing.T) {
 	t.Parallel()
-	buf := new(bytes.Buffer)
-	out, err := script.Echo("go").WithStderr(buf).ExecForEach(func(line string) (string, []string) { return line, []string{} }).String()
+	want := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0}
+	got, err := script.NewPipe().WithReader(bytes.NewReader(want)).DataURI("image/png").String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if out != "" {
-		t.Fatalf("unexpected output: %q", out)
+	prefix := "data:image/png;base64,"
+	if !strings.HasPrefix(got, prefix) {
+		t.Fatalf("want prefix %q, got %q", prefix, got)
 	}
-	if !strings.Contains(buf.String(), "Usage") {
-		t.Errorf("want stderr output containing the word 'Usage', got %q", buf.String())
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(got, prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(want, decoded) {
+		t.Errorf("want %v, got %v", want, decoded)
 	}
 }
 
-func TestExecSendsStderrOutputToPipeStderr(t *testing.T) {
+func TestDedupOutputsEachDistinctLineOnceInFirstSeenOrder(t *testing.T) {
 	t.Parallel()
-	buf := new(bytes.Buffer)
-	out, err := script.NewPipe().WithStderr(buf).Exec("go").String()
-	if err == nil {
-		t.Fatal("want error when command returns a non-zero exit status")
-	}
-	if out != "" {
-		t.Fatalf("unexpected output: %q", out)
+	want := "b\na\nc\n\n"
+	got, err := script.Echo("b\na\nb\nc\na\n\nc\n\n").Dedup().String()
+	if err != nil {
+		t.Fatal(err)
 	}
-	if !strings.Contains(buf.String(), "Usage") {
-		t.Errorf("want stderr output containing the word 'Usage', got %q", buf.String())
+	if want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestFilterByCopyPassesInputThroughUnchanged(t *testing.T) {
+func TestDetectGapsReportsRangesOfMissingSequenceNumbers(t *testing.T) {
 	t.Parallel()
-	p := script.Echo("hello").Filter(func(r io.Reader, w io.Writer) error {
-		_, err := io.Copy(w, r)
-		return err
-	})
-	want := "hello"
-	got, err := p.String()
+	input := strings.Join([]string{
+		"rec 1",
+		"rec 2",
+		"rec 3",
+		"rec 7",
+		"rec n/a",
+		"short",
+		"rec 8",
+		"rec 10",
+		"rec 10",
+		"rec 11",
+	}, "\n")
+	want := "4-6\n9\n"
+	got, err := script.Echo(input).DetectGaps(2).String()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -379,13 +572,295 @@ func TestFilterByCopyPassesInputThroughUnchanged(t *testing.T) {
 	}
 }
 
-func TestFilterCanChainFilters(t *testing.T) {
+func TestDiffFile_ProducesNoOutputGivenIdenticalContent(t *testing.T) {
 	t.Parallel()
-	p := script.Echo("hello").Filter(func(r io.Reader, w io.Writer) error {
-		_, err := io.Copy(w, r)
-		return err
-	}).Filter(func(r io.Reader, w io.Writer) error {
-		_, err := io.Copy(w, r)
+	p := script.File("testdata/test.txt").DiffFileStrict("testdata/test.txt")
+	got, err := p.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "" {
+		t.Errorf("want no output, got %q", got)
+	}
+}
+
+func TestDiffFile_ProducesUnifiedDiffGivenDifferingContent(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "golden.txt")
+	err := os.WriteFile(path, []byte("a\nb\nc\nd\ne\nf\ng\nh\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--- " + path + "\n+++ -\n@@ -1,7 +1,7 @@\n a\n b\n c\n-d\n+D\n e\n f\n g\n"
+	got, err := script.Echo("a\nb\nc\nD\ne\nf\ng\nh\n").DiffFile(path).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestDiffFile_ReplacesDifferingLinesWholesaleGivenTooManyChanges(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "golden.txt")
+	var golden, input, removed, added strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&golden, "old %d\n", i)
+		fmt.Fprintf(&input, "new %d\n", i)
+		fmt.Fprintf(&removed, "-old %d\n", i)
+		fmt.Fprintf(&added, "+new %d\n", i)
+	}
+	err := os.WriteFile(path, []byte("first\n"+golden.String()+"last\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--- " + path + "\n+++ -\n@@ -1,1002 +1,1002 @@\n first\n" + removed.String() + added.String() + " last\n"
+	got, err := script.Echo("first\n" + input.String() + "last\n").DiffFile(path).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestDiffFileStrict_SetsErrorGivenDifferingContent(t *testing.T) {
+	t.Parallel()
+	p := script.Echo("something else\n").DiffFileStrict("testdata/hello.txt")
+	p.Wait()
+	if p.Error() == nil {
+		t.Error("want error when contents differ in strict mode")
+	}
+}
+
+func TestDirname_RemovesFilenameComponentFromInputLines(t *testing.T) {
+	t.Parallel()
+	tcs := []struct {
+		path string
+		want string
+	}{
+		{"/", "/\n"},
+		{"./", ".\n"},
+		{"\n", ".\n"},
+		{"/root", "/\n"},
+		{"/tmp/example.php", "/tmp\n"},
+		{"/var/tmp/example.php", "/var/tmp\n"},
+		{"/var/tmp", "/var\n"},
+		{"/var/tmp/", "/var\n"},
+		{"src/filters", "./src\n"},
+		{"src/filters/", "./src\n"},
+		{"/tmp/script-21345.txt\n/tmp/script-5371253.txt", "/tmp\n/tmp\n"},
+		{"C:/Program Files/PHP", "C:/Program Files\n"},
+		{"C:/Program Files/PHP/", "C:/Program Files\n"},
+	}
+	for _, tc := range tcs {
+		// Expect results to use this platform's path separator
+		want := filepath.Clean(tc.want)
+		got, err := script.Echo(tc.path).Dirname().String()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want != got {
+			t.Errorf("%q: want %q, got %q", tc.path, want, got)
+		}
+	}
+}
+
+func TestDistinctURLsTreatsReorderedQueryParametersAsEqual(t *testing.T) {
+	t.Parallel()
+	input := strings.Join([]string{
+		"https://example.com/a?x=1&y=2",
+		"https://example.com/a?y=2&x=1",
+		"https://example.com/b?x=1",
+		"https://example.com/a?x=1&y=3",
+		"https://example.com/b?x=1",
+	}, "\n")
+	want := "https://example.com/a?x=1&y=2\nhttps://example.com/b?x=1\nhttps://example.com/a?x=1&y=3\n"
+	got, err := script.Echo(input).DistinctURLs().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestDoPerformsSuppliedHTTPRequest(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "some data")
+	}))
+	defer ts.Close()
+	req, err := http.NewRequest(http.MethodGet, ts.URL, http.NoBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "some data\n"
+	got, err := script.Do(req).String()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestEachLine_FiltersInputThroughSuppliedFunction(t *testing.T) {
+	t.Parallel()
+	want := "Hello world\nGoodbye world\n"
+	got, err := script.Echo("Hello\nGoodbye").
+		EachLine(func(line string, out *strings.Builder) {
+			out.WriteString(line + " world\n")
+		}).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestEachLine_HandlesLongLines(t *testing.T) {
+	t.Parallel()
+	var got int
+	_, err := script.Echo(longLine).
+		EachLine(func(line string, out *strings.Builder) {
+			got++
+		}).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := 2
+	if want != got {
+		t.Errorf("want %d lines counted, got %d", want, got)
+	}
+}
+
+func TestEchoProducesSuppliedString(t *testing.T) {
+	t.Parallel()
+	want := "Hello, world."
+	p := script.Echo(want)
+	got, err := p.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestEchoReplacesInputWithSuppliedStringWhenUsedAsFilter(t *testing.T) {
+	t.Parallel()
+	want := "Hello, world."
+	p := script.Echo("bogus").Echo(want)
+	got, err := p.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestExecForEach_ErrorsOnInvalidTemplateSyntax(t *testing.T) {
+	t.Parallel()
+	p := script.Echo("a\nb\nc\n").ExecForEach(func(line string) (string, []string) { return "", nil })
+	p.Wait()
+	if p.Error() == nil {
+		t.Error("want error with invalid template syntax")
+	}
+}
+
+func TestExecForEach_ErrorsOnUnbalancedQuotes(t *testing.T) {
+	t.Parallel()
+	p := script.Echo("a\nb\nc\n").ExecForEach(func(line string) (string, []string) { return "echo", []string{line} })
+	p.Wait()
+	if p.Error() == nil {
+		t.Error("want error with unbalanced quotes in command line")
+	}
+}
+
+func TestExecForEach_SendsStderrOutputToPipeStderr(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	out, err := script.Echo("go").WithStderr(buf).ExecForEach(func(line string) (string, []string) { return line, []string{} }).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+	if !strings.Contains(buf.String(), "Usage") {
+		t.Errorf("want stderr output containing the word 'Usage', got %q", buf.String())
+	}
+}
+
+func TestExecSendsStderrOutputToPipeStderr(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	out, err := script.NewPipe().WithStderr(buf).Exec("go").String()
+	if err == nil {
+		t.Fatal("want error when command returns a non-zero exit status")
+	}
+	if out != "" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+	if !strings.Contains(buf.String(), "Usage") {
+		t.Errorf("want stderr output containing the word 'Usage', got %q", buf.String())
+	}
+}
+
+func TestExtractEmailsOutputsEmailAddressesFoundInText(t *testing.T) {
+	t.Parallel()
+	input := "Contact alice@example.com or Bob.Smith+news@mail.example.co.uk.\nNot an email: foo@bar, @handle\n"
+	want := "alice@example.com\nBob.Smith+news@mail.example.co.uk\n"
+	got, err := script.Echo(input).ExtractEmails().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestExtractURLsOutputsURLsFoundInText(t *testing.T) {
+	t.Parallel()
+	input := "See https://example.com/docs?page=2. Also (http://foo.example/a_(b)) and\n" +
+		"<a href=\"https://bar.example/x\">link</a>, but not ftp://nope.example or mailto:x@y.z!\n"
+	want := "https://example.com/docs?page=2\nhttp://foo.example/a_(b)\nhttps://bar.example/x\n"
+	got, err := script.Echo(input).ExtractURLs().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestFilterByCopyPassesInputThroughUnchanged(t *testing.T) {
+	t.Parallel()
+	p := script.Echo("hello").Filter(func(r io.Reader, w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+	want := "hello"
+	got, err := p.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestFilterCanChainFilters(t *testing.T) {
+	t.Parallel()
+	p := script.Echo("hello").Filter(func(r io.Reader, w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	}).Filter(func(r io.Reader, w io.Writer) error {
+		_, err := io.Copy(w, r)
 		return err
 	})
 	want := "hello"
@@ -591,14 +1066,93 @@ func TestFirstDoesNotConsumeUnnecessaryData(t *testing.T) {
 	}
 }
 
-func TestFreqHandlesLongLines(t *testing.T) {
+func TestFirstNWritesFirstNLinesAndReturnsLinesWritten(t *testing.T) {
 	t.Parallel()
-	got, err := script.Echo(longLine).Freq().Slice()
-	if err != nil {
-		t.Fatal(err)
+	tcs := []struct {
+		n, want int
+		output  string
+	}{
+		{n: 2, want: 2, output: "a\nb\n"},
+		{n: 5, want: 3, output: "a\nb\nc\n"},
+		{n: 0, want: 0, output: ""},
 	}
-	if len(got) != 2 {
-		t.Fatalf("want 2 results, got %d: %q", len(got), got)
+	for _, tc := range tcs {
+		buf := new(bytes.Buffer)
+		got, err := script.Echo("a\nb\nc\n").WithStdout(buf).FirstN(tc.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.want != got {
+			t.Errorf("FirstN(%d): want %d lines, got %d", tc.n, tc.want, got)
+		}
+		if tc.output != buf.String() {
+			t.Error(cmp.Diff(tc.output, buf.String()))
+		}
+	}
+}
+
+func TestFirstByColumnOutputsFirstLineForEachColumnValue(t *testing.T) {
+	t.Parallel()
+	input := strings.Join([]string{
+		"1 GET /a",
+		"2 GET /b",
+		"3 POST /a",
+		"4 GET",
+		"5 PUT /c",
+		"6 GET /b",
+	}, "\n")
+	want := "1 GET /a\n2 GET /b\n5 PUT /c\n"
+	got, err := script.Echo(input).FirstByColumn(3).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestFirstMatchEachOutputsFirstLineMatchingEachPattern(t *testing.T) {
+	t.Parallel()
+	input := strings.Join([]string{
+		"Date: today",
+		"Host: example.com",
+		"Date: yesterday",
+		"From: Host: someone",
+		"From: someone else",
+	}, "\n")
+	want := "Date: today\nHost: example.com\nFrom: Host: someone\n"
+	got, err := script.Echo(input).FirstMatchEach("Date:", "Host:", "From:").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestFirstMatchEachStopsReadingOnceAllPatternsMatch(t *testing.T) {
+	t.Parallel()
+	r := strings.NewReader("a\nb\n" + strings.Repeat("line\n", 10000))
+	got, err := script.NewPipe().WithReader(r).FirstMatchEach("a", "b").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "a\nb\n" {
+		t.Errorf("want %q, got %q", "a\nb\n", got)
+	}
+	if r.Len() == 0 {
+		t.Error("no data left in reader")
+	}
+}
+
+func TestFreqHandlesLongLines(t *testing.T) {
+	t.Parallel()
+	got, err := script.Echo(longLine).Freq().Slice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("want 2 results, got %d: %q", len(got), got)
 	}
 	if got[0] != "1 last line" {
 		t.Fatalf("wrong result: %q", got)
@@ -638,6 +1192,26 @@ func TestFreqProducesCorrectFrequencyTableForInput(t *testing.T) {
 	}
 }
 
+func TestFreqCSVQuotesValuesContainingCommas(t *testing.T) {
+	t.Parallel()
+	input := strings.Join([]string{
+		"apple",
+		"Smith, John",
+		"say \"hi\"",
+		"Smith, John",
+		"apple",
+		"Smith, John",
+	}, "\n")
+	want := "3,\"Smith, John\"\n2,apple\n1,\"say \"\"hi\"\"\"\n"
+	got, err := script.Echo(input).FreqCSV().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
 func TestGetMakesHTTPGetRequestToGivenURL(t *testing.T) {
 	t.Parallel()
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -705,6 +1279,249 @@ func TestGetUsesPipeContentsAsRequestBody(t *testing.T) {
 	}
 }
 
+func TestGetAll_OutputsResponsesInURLOrder(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Make earlier URLs respond later, so completion order differs from URL order
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		time.Sleep(time.Duration(10-n) * 5 * time.Millisecond)
+		fmt.Fprintln(w, "page", n)
+	}))
+	defer ts.Close()
+	var urls []string
+	want := ""
+	for i := 0; i < 10; i++ {
+		urls = append(urls, fmt.Sprintf("%s/%d", ts.URL, i))
+		want += fmt.Sprintf("page %d\n", i)
+	}
+	got, err := script.GetAll(urls, 4).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGetAll_StopsMakingRequestsWhenOutputCannotBeWritten(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprintln(w, "page")
+	}))
+	defer ts.Close()
+	urls := make([]string, 100)
+	for i := range urls {
+		urls[i] = ts.URL
+	}
+	program := xstd.GetAll(urls, 2, ts.Client())
+	program.SetStdin(strings.NewReader(""))
+	program.SetStdout(errorWriter{})
+	program.SetStderr(io.Discard)
+	if err := program.Start(); err == nil {
+		t.Fatal("want error given failing output")
+	}
+	if n := atomic.LoadInt32(&requests); n >= 100 {
+		t.Errorf("want remaining requests not made, got %d", n)
+	}
+}
+
+func TestGetAll_SetsErrorWhenAnyRequestFails(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bad" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	}))
+	defer ts.Close()
+	got, err := script.GetAll([]string{ts.URL + "/a", ts.URL + "/bad", ts.URL + "/b"}, 2).String()
+	if err == nil {
+		t.Error("want error when a request fails")
+	}
+	if got != "ok\nok\n" {
+		t.Errorf("want remaining responses, got %q", got)
+	}
+}
+
+func TestGzipOutputIsDecompressedByGunzip(t *testing.T) {
+	t.Parallel()
+	want := strings.Repeat("hello, world\n", 1000)
+	compressed, err := script.Echo(want).Gzip(gzip.BestCompression).Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compressed) >= len(want) {
+		t.Errorf("want compressed data smaller than %d bytes, got %d", len(want), len(compressed))
+	}
+	got, err := script.NewPipe().WithReader(bytes.NewReader(compressed)).Gunzip().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGunzipDecompressesConcatenatedMembers(t *testing.T) {
+	t.Parallel()
+	first, err := script.Echo("first\n").Gzip(gzip.DefaultCompression).Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := script.Echo("second\n").Gzip(gzip.DefaultCompression).Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "first\nsecond\n"
+	got, err := script.NewPipe().WithReader(bytes.NewReader(append(first, second...))).Gunzip().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestGunzipSetsErrorGivenInvalidInput(t *testing.T) {
+	t.Parallel()
+	p := script.Echo("not gzip data").Gunzip()
+	p.Wait()
+	if p.Error() == nil {
+		t.Error("want error given invalid gzip data")
+	}
+}
+
+func TestGzipSetsErrorGivenInvalidLevel(t *testing.T) {
+	t.Parallel()
+	p := script.Echo("hello").Gzip(42)
+	p.Wait()
+	if p.Error() == nil {
+		t.Error("want error given invalid compression level")
+	}
+}
+
+func TestHeadOutputsFirstNLinesGivenEnoughInput(t *testing.T) {
+	t.Parallel()
+	want := "a\nb\n"
+	got, err := script.Echo("a\nb\nc\n").Head(2).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestHeadSetsErrorGivenFewerThanNLines(t *testing.T) {
+	t.Parallel()
+	want := "a\nb\n"
+	got, err := script.Echo("a\nb\n").Head(3).String()
+	if err == nil {
+		t.Error("want error given fewer than 3 lines")
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestHexEncodeEncodesInputAsHex(t *testing.T) {
+	t.Parallel()
+	want := "00ff48690a"
+	got, err := script.NewPipe().WithReader(bytes.NewReader([]byte{0, 255, 'H', 'i', '\n'})).HexEncode().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestHexDecodeDecodesHexIgnoringNewlines(t *testing.T) {
+	t.Parallel()
+	want := []byte{0, 255, 'H', 'i'}
+	got, err := script.Echo("00ff\n4869\n").HexDecode().Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestHexDecodeSetsErrorGivenMalformedHex(t *testing.T) {
+	t.Parallel()
+	for _, input := range []string{"00zz", "abc"} {
+		p := script.Echo(input).HexDecode()
+		p.Wait()
+		if p.Error() == nil {
+			t.Errorf("%q: want error given malformed hex", input)
+		}
+	}
+}
+
+func TestHumanizeDurationRewritesColumnAsDuration(t *testing.T) {
+	t.Parallel()
+	input := "build 3723 ok\ntest 0.25 ok\nlint n/a ok\nshort\n"
+	want := "build 1h2m3s ok\ntest 250ms ok\nlint n/a ok\nshort\n"
+	got, err := script.Echo(input).HumanizeDuration(2).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+	got, err = script.Echo("req 1500\n").HumanizeDurationUnit(2, time.Nanosecond).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "req 1.5µs\n"; want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestHumanizeDurationLeavesOutOfRangeValuesUnchanged(t *testing.T) {
+	t.Parallel()
+	input := "a 1e300\nb -1e300\nc NaN\nd Inf\n"
+	got, err := script.Echo(input).HumanizeDuration(2).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if input != got {
+		t.Error(cmp.Diff(input, got))
+	}
+}
+
+func TestJoinOnJoinsLinesWithMatchingKeys(t *testing.T) {
+	t.Parallel()
+	events := script.Echo("login u1\nlogout u2\nlogin u3\nbroken\nlogin u1\n")
+	users := script.Echo("u1 alice admin\nu2 bob\nu1 alice-alt\n")
+	want := strings.Join([]string{
+		"login u1 | u1 alice admin",
+		"login u1 | u1 alice-alt",
+		"logout u2 | u2 bob",
+		"login u1 | u1 alice admin",
+		"login u1 | u1 alice-alt",
+	}, "\n") + "\n"
+	got, err := script.JoinOn(events, users, 2, 1, " | ").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestJoinOnSetsErrorFromRightPipe(t *testing.T) {
+	t.Parallel()
+	_, err := script.JoinOn(script.Echo("a 1\n"), script.File("doesntexist"), 2, 1, " ").String()
+	if err == nil {
+		t.Error("want error from right pipe")
+	}
+}
+
 func TestJoinHandlesLongLines(t *testing.T) {
 	t.Parallel()
 	result, err := script.Echo(longLine).Join().String()
@@ -810,11 +1627,22 @@ func TestJoinJoinsInputLinesIntoSpaceSeparatedString(t *testing.T) {
 // 	}
 // }
 
-func TestLastDropsAllButLastNLinesOfInput(t *testing.T) {
+func TestJSONColorWithHighlightsTokensByType(t *testing.T) {
 	t.Parallel()
-	input := "a\nb\nc\n"
-	want := "b\nc\n"
-	got, err := script.Echo(input).Last(2).String()
+	input := `{"name":"a<b","n":1.50,"ok":true,"none":null,"list":[],"obj":{"x":[false]}}`
+	want := "{\n" +
+		"  \x1b[34;1m\"name\"\x1b[0m: \x1b[32m\"a<b\"\x1b[0m,\n" +
+		"  \x1b[34;1m\"n\"\x1b[0m: \x1b[36m1.50\x1b[0m,\n" +
+		"  \x1b[34;1m\"ok\"\x1b[0m: \x1b[33mtrue\x1b[0m,\n" +
+		"  \x1b[34;1m\"none\"\x1b[0m: \x1b[90mnull\x1b[0m,\n" +
+		"  \x1b[34;1m\"list\"\x1b[0m: [],\n" +
+		"  \x1b[34;1m\"obj\"\x1b[0m: {\n" +
+		"    \x1b[34;1m\"x\"\x1b[0m: [\n" +
+		"      \x1b[33mfalse\x1b[0m\n" +
+		"    ]\n" +
+		"  }\n" +
+		"}\n"
+	got, err := script.Echo(input).JSONColorWith(true).String()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -823,10 +1651,10 @@ func TestLastDropsAllButLastNLinesOfInput(t *testing.T) {
 	}
 }
 
-func TestLastHandlesLongLines(t *testing.T) {
+func TestJSONColorWithPrettyPrintsWithoutColorWhenDisabled(t *testing.T) {
 	t.Parallel()
-	want := "last line\n"
-	got, err := script.Echo(longLine).Last(1).String()
+	want := "{\n  \"a\": [\n    1,\n    \"x\"\n  ]\n}\n42\n"
+	got, err := script.Echo(`{"a":[1,"x"]} 42`).JSONColorWith(false).String()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -835,11 +1663,18 @@ func TestLastHandlesLongLines(t *testing.T) {
 	}
 }
 
-func TestLastHasNoOutputWhenNIs0(t *testing.T) {
+func TestJSONColorSetsErrorGivenInvalidJSON(t *testing.T) {
 	t.Parallel()
-	input := "a\nb\nc\n"
-	want := ""
-	got, err := script.Echo(input).Last(0).String()
+	_, err := script.Echo(`{"a":`).JSONColorWith(false).String()
+	if err == nil {
+		t.Fatal("want error given invalid JSON")
+	}
+}
+
+func TestJSONToLinesOutputsEachArrayElementOnItsOwnLine(t *testing.T) {
+	t.Parallel()
+	want := "plain\nsay \"hi\"\né\n"
+	got, err := script.Echo(`["plain", "say \"hi\"", "é"]`).JSONToLines().String()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -848,11 +1683,19 @@ func TestLastHasNoOutputWhenNIs0(t *testing.T) {
 	}
 }
 
-func TestLastHasNoOutputWhenNIsNegative(t *testing.T) {
+func TestJSONToLinesSetsErrorGivenNonStringArray(t *testing.T) {
 	t.Parallel()
-	input := "a\nb\nc\n"
-	want := ""
-	got, err := script.Echo(input).Last(-1).String()
+	p := script.Echo(`[1, 2]`).JSONToLines()
+	p.Wait()
+	if p.Error() == nil {
+		t.Error("want error given array of numbers")
+	}
+}
+
+func TestJSONKeysOutputsSortedTopLevelKeys(t *testing.T) {
+	t.Parallel()
+	want := "alpha\nbeta\nzeta\n"
+	got, err := script.Echo(`{"zeta": 1, "alpha": {"nested": true}, "beta": [1, 2]}`).JSONKeys().String()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -861,11 +1704,24 @@ func TestLastHasNoOutputWhenNIsNegative(t *testing.T) {
 	}
 }
 
-func TestLastHasNoEffectGivenLessThanNInputLines(t *testing.T) {
+func TestJSONKeysSetsErrorGivenNonObject(t *testing.T) {
 	t.Parallel()
-	input := "a\nb\nc\n"
-	want := "a\nb\nc\n"
-	got, err := script.Echo(input).Last(4).String()
+	p := script.Echo(`[1, 2, 3]`).JSONKeys()
+	p.Wait()
+	if p.Error() == nil {
+		t.Error("want error given JSON array")
+	}
+}
+
+func TestJSONKeysDeepOutputsSortedPathsToAllLeaves(t *testing.T) {
+	t.Parallel()
+	input := `{
+		"server": {"host": "localhost", "port": 8080, "tls": {}},
+		"users": [{"name": "a"}, {"name": "b", "admin": true}],
+		"debug": false
+	}`
+	want := "debug\nserver.host\nserver.port\nserver.tls\nusers.0.name\nusers.1.admin\nusers.1.name\n"
+	got, err := script.Echo(input).JSONKeysDeep().String()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -874,17 +1730,191 @@ func TestLastHasNoEffectGivenLessThanNInputLines(t *testing.T) {
 	}
 }
 
-func TestMatchOutputsOnlyMatchingLinesOfInput(t *testing.T) {
+func TestJSONNumbersToStringsPreservesPrecisionOf64BitIntegers(t *testing.T) {
 	t.Parallel()
-	input := "This is the first line in the file.\nHello, world.\nThis is another line in the file.\n"
-	tcs := []struct {
-		match, want string
-	}{
-		{
-			match: "line",
-			want:  "This is the first line in the file.\nThis is another line in the file.\n",
-		},
-		{
+	input := `{"id":9223372036854775807,"items":[{"n":1.50},"x",true,null]}`
+	want := `{"id":"9223372036854775807","items":[{"n":"1.50"},"x",true,null]}` + "\n"
+	got, err := script.Echo(input).JSONNumbersToStrings().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestJSONPrettyStreamingIndentsInputWithinLimit(t *testing.T) {
+	t.Parallel()
+	input := `{"a":[1,2],"b":{}}`
+	want := "{\n\t\"a\": [\n\t\t1,\n\t\t2\n\t],\n\t\"b\": {}\n}\n"
+	got, err := script.Echo(input).JSONPrettyStreaming("\t", int64(len(input))).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestJSONPrettyStreamingSetsErrorGivenInputOverLimit(t *testing.T) {
+	t.Parallel()
+	input := `{"a":[1,2],"b":{}}`
+	got, err := script.Echo(input).JSONPrettyStreaming("  ", int64(len(input)-1)).String()
+	if err == nil {
+		t.Fatal("want error given input over limit")
+	}
+	if got != "" {
+		t.Errorf("want no output, got %q", got)
+	}
+}
+
+func TestJSONPrettyStreamingHasNoLimitGivenMaxInt64(t *testing.T) {
+	t.Parallel()
+	want := "{\n  \"a\": 1\n}\n"
+	got, err := script.Echo(`{"a":1}`).JSONPrettyStreaming("  ", math.MaxInt64).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestJSONPrettyStreamingSetsErrorGivenNegativeLimit(t *testing.T) {
+	t.Parallel()
+	p := script.Echo(`{"a":1}`).JSONPrettyStreaming("  ", -5)
+	p.Wait()
+	if p.Error() == nil {
+		t.Error("want error given negative limit")
+	}
+}
+
+func TestJSONStringsToNumbersConvertsNumericStringsBack(t *testing.T) {
+	t.Parallel()
+	input := `{"id":"9223372036854775807","neg":"-1.5e3","name":"007","word":"12abc"}`
+	want := `{"id":9223372036854775807,"name":"007","neg":-1.5e3,"word":"12abc"}` + "\n"
+	got, err := script.Echo(input).JSONStringsToNumbers().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestLastDropsAllButLastNLinesOfInput(t *testing.T) {
+	t.Parallel()
+	input := "a\nb\nc\n"
+	want := "b\nc\n"
+	got, err := script.Echo(input).Last(2).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestLastHandlesLongLines(t *testing.T) {
+	t.Parallel()
+	want := "last line\n"
+	got, err := script.Echo(longLine).Last(1).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestLastHasNoOutputWhenNIs0(t *testing.T) {
+	t.Parallel()
+	input := "a\nb\nc\n"
+	want := ""
+	got, err := script.Echo(input).Last(0).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestLastHasNoOutputWhenNIsNegative(t *testing.T) {
+	t.Parallel()
+	input := "a\nb\nc\n"
+	want := ""
+	got, err := script.Echo(input).Last(-1).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestLastHasNoEffectGivenLessThanNInputLines(t *testing.T) {
+	t.Parallel()
+	input := "a\nb\nc\n"
+	want := "a\nb\nc\n"
+	got, err := script.Echo(input).Last(4).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestMarkdownCodeBlockWrapsInputInFence(t *testing.T) {
+	t.Parallel()
+	want := "```sh\n$ go test\nok\n```\n"
+	got, err := script.Echo("$ go test\nok").MarkdownCodeBlock("sh").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestMarkdownCodeBlockLengthensFenceWhenInputContainsBackticks(t *testing.T) {
+	t.Parallel()
+	input := "Example:\n```go\nfmt.Println(\"hi\")\n````\n"
+	want := "`````markdown\n" + input + "`````\n"
+	got, err := script.Echo(input).MarkdownCodeBlock("markdown").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestMapCollectsKeyAndValueColumnsSkippingShortLines(t *testing.T) {
+	t.Parallel()
+	input := "alice 1001 admin\nbob 1002 dev\ncarol\nalice 1003 ops\n"
+	want := map[string]string{"alice": "ops", "bob": "dev"}
+	got, err := script.Echo(input).Map(1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestMatchOutputsOnlyMatchingLinesOfInput(t *testing.T) {
+	t.Parallel()
+	input := "This is the first line in the file.\nHello, world.\nThis is another line in the file.\n"
+	tcs := []struct {
+		match, want string
+	}{
+		{
+			match: "line",
+			want:  "This is the first line in the file.\nThis is another line in the file.\n",
+		},
+		{
 			match: "another",
 			want:  "This is another line in the file.\n",
 		},
@@ -915,6 +1945,106 @@ func TestMatchOutputsNothingGivenEmptyInput(t *testing.T) {
 	}
 }
 
+func TestMatchFoldOutputsLinesMatchingIgnoringCase(t *testing.T) {
+	t.Parallel()
+	input := "Hello World\nHELLO there\ngoodbye\nΣΊΣΥΦΟΣ\n\u212aelvin\n"
+	tcs := []struct {
+		s    string
+		want string
+	}{
+		{"hello", "Hello World\nHELLO there\n"},
+		{"WORLD", "Hello World\n"},
+		{"σίσυφος", "ΣΊΣΥΦΟΣ\n"},
+		{"kelvin", "\u212aelvin\n"},
+		{"nope", ""},
+	}
+	for _, tc := range tcs {
+		got, err := script.Echo(input).MatchFold(tc.s).String()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.want != got {
+			t.Errorf("%q: %s", tc.s, cmp.Diff(tc.want, got))
+		}
+	}
+}
+
+func TestRejectBinary_OutputsOnlyPathsOfTextFiles(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	text := filepath.Join(dir, "text.txt")
+	binary := filepath.Join(dir, "binary.dat")
+	// A multi-byte rune straddling the sniffed prefix must not count as invalid
+	long := filepath.Join(dir, "long.txt")
+	if err := os.WriteFile(text, []byte("hello, world\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(binary, []byte("hello\x00world\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(long, []byte(strings.Repeat("x", 1023)+"é"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	input := strings.Join([]string{text, binary, long, filepath.Join(dir, "doesntexist"), dir}, "\n")
+	want := text + "\n" + long + "\n"
+	got, err := script.Echo(input).RejectBinary().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestRejectFoldDropsLinesMatchingIgnoringCase(t *testing.T) {
+	t.Parallel()
+	want := "goodbye\n"
+	got, err := script.Echo("Hello World\nHELLO there\ngoodbye\n").RejectFold("hElLo").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestMatchContextOutputsMatchingLinesWithContext(t *testing.T) {
+	t.Parallel()
+	input := "1\n2\n3 match\n4\n5\n6\n7\n8\n9 match\n10 match\n11\n12\n"
+	tcs := []struct {
+		before, after int
+		want          string
+	}{
+		{0, 0, "3 match\n--\n9 match\n10 match\n"},
+		{1, 1, "2\n3 match\n4\n--\n8\n9 match\n10 match\n11\n"},
+		{2, 0, "1\n2\n3 match\n--\n7\n8\n9 match\n10 match\n"},
+		{0, 3, "3 match\n4\n5\n6\n--\n9 match\n10 match\n11\n12\n"},
+		{3, 3, "1\n2\n3 match\n4\n5\n6\n7\n8\n9 match\n10 match\n11\n12\n"},
+	}
+	for _, tc := range tcs {
+		got, err := script.Echo(input).MatchContext("match", tc.before, tc.after).String()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.want != got {
+			t.Errorf("-B %d -A %d: %s", tc.before, tc.after, cmp.Diff(tc.want, got))
+		}
+	}
+}
+
+func TestMatchRegexpContextOutputsMatchingLinesWithContext(t *testing.T) {
+	t.Parallel()
+	input := "a\nb\nERROR 1\nc\nd\ne\nWARN 2\nf\n"
+	want := "b\nERROR 1\nc\n--\ne\nWARN 2\nf\n"
+	got, err := script.Echo(input).MatchRegexpContext(regexp.MustCompile(`^(ERROR|WARN)`), 1, 1).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
 func TestMatchRegexp_OutputsOnlyLinesMatchingRegexp(t *testing.T) {
 	t.Parallel()
 	input := "This is the first line in the file.\nHello, world.\nThis is another line in the file.\n"
@@ -991,6 +2121,29 @@ func TestReplaceReplacesMatchesWithSpecifiedText(t *testing.T) {
 	}
 }
 
+func TestReplaceNReplacesOnlyFirstNOccurrencesInEachLine(t *testing.T) {
+	t.Parallel()
+	input := "a-b-c-d\n-x-\n"
+	tcs := []struct {
+		n    int
+		want string
+	}{
+		{n: 0, want: "a-b-c-d\n-x-\n"},
+		{n: 1, want: "a+b-c-d\n+x-\n"},
+		{n: 2, want: "a+b+c-d\n+x+\n"},
+		{n: -1, want: "a+b+c+d\n+x+\n"},
+	}
+	for _, tc := range tcs {
+		got, err := script.Echo(input).ReplaceN("-", "+", tc.n).String()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.want != got {
+			t.Errorf("n=%d: %s", tc.n, cmp.Diff(tc.want, got))
+		}
+	}
+}
+
 func TestReplaceRegexp_ReplacesMatchesWithSpecifiedText(t *testing.T) {
 	t.Parallel()
 	input := "hello world"
@@ -1029,6 +2182,22 @@ func TestReplaceRegexp_ReplacesMatchesWithSpecifiedText(t *testing.T) {
 	}
 }
 
+func TestReplaceRegexpFuncReplacesMatchesWithResultOfCallback(t *testing.T) {
+	t.Parallel()
+	re := regexp.MustCompile(`v[0-9]+`)
+	want := "v2 and v11\nnone\n"
+	got, err := script.Echo("v1 and v10\nnone\n").ReplaceRegexpFunc(re, func(match string) string {
+		n, _ := strconv.Atoi(match[1:])
+		return fmt.Sprintf("v%d", n+1)
+	}).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
 func TestRejectDropsMatchingLinesFromInput(t *testing.T) {
 	t.Parallel()
 	input := "This is the first line in the file.\nHello, world.\nThis is another line in the file.\n"
@@ -1059,589 +2228,1791 @@ func TestRejectDropsMatchingLinesFromInput(t *testing.T) {
 	}
 }
 
-func TestPostPostsToGivenURLUsingPipeAsRequestBody(t *testing.T) {
+func TestNewSinceOutputsOnlyLinesAddedSincePreviousRun(t *testing.T) {
 	t.Parallel()
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			t.Errorf("want HTTP method POST, got %q", r.Method)
-		}
-		want := []byte("request data")
-		got, err := io.ReadAll(r.Body)
-		if err != nil {
-			t.Fatal("reading request body", err)
-		}
-		if !cmp.Equal(want, got) {
-			t.Fatal(cmp.Diff(want, string(got)))
-		}
-		fmt.Fprintln(w, "response data")
-	}))
-	defer ts.Close()
-	want := "response data\n"
-	got, err := script.Echo("request data").Post(ts.URL).String()
+	path := filepath.Join(t.TempDir(), "snapshot.txt")
+	got, err := script.Echo("a\nb\nb\n").NewSince(path).String()
 	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+		t.Fatal(err)
 	}
-	if !cmp.Equal(want, got) {
+	if want := "a\nb\n"; want != got {
 		t.Error(cmp.Diff(want, got))
 	}
-}
-
-func TestRejectRegexp_DropsMatchingLinesFromInput(t *testing.T) {
+	got, err = script.Echo("b\nc\na\n").NewSince(path).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "c\n"; want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+	snapshot, err := script.File(path).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "b\nc\na\n"; want != snapshot {
+		t.Error(cmp.Diff(want, snapshot))
+	}
+}
+
+func TestRemovedSinceOutputsOnlyLinesRemovedSincePreviousRun(t *testing.T) {
 	t.Parallel()
-	input := "hello world"
-	tcs := []struct {
-		regex, want string
-	}{
-		{
-			regex: `Hello|line`,
-			want:  "hello world\n",
-		},
-		{
-			regex: `hello|bogus`,
-			want:  "",
-		},
-		{
-			regex: `w.*d`,
-			want:  "",
-		},
-		{
-			regex: "wontmatch",
-			want:  "hello world\n",
-		},
+	path := filepath.Join(t.TempDir(), "snapshot.txt")
+	got, err := script.Echo("a\nb\nc\n").RemovedSince(path).String()
+	if err != nil {
+		t.Fatal(err)
 	}
-	for _, tc := range tcs {
-		got, err := script.Echo(input).RejectRegexp(regexp.MustCompile(tc.regex)).String()
-		if err != nil {
-			t.Fatal(err)
-		}
-		if tc.want != got {
-			t.Error(cmp.Diff(tc.want, got))
-		}
+	if want := ""; want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+	got, err = script.Echo("c\na\nd\n").RemovedSince(path).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "b\n"; want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestSHA256Sums_OutputsCorrectHashForEachSpecifiedFile(t *testing.T) {
+func TestNearestLineReturnsLineWithSmallestEditDistance(t *testing.T) {
 	t.Parallel()
+	input := "build\ncommit\ncheckout\nclone\nbat\ncot\n"
 	tcs := []struct {
-		testFileName string
-		want         string
+		target, want string
 	}{
-		// To get the checksum run: openssl dgst -sha256 <file_name>
-		{"testdata/sha256Sum.input.txt", "1870478d23b0b4db37735d917f4f0ff9393dd3e52d8b0efa852ab85536ddad8e\n"},
-		{"testdata/hello.txt", "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9\n"},
-		{"testdata/multiple_files", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\ne3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\ne3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\n"},
+		{target: "comit", want: "commit"},
+		{target: "chekout", want: "checkout"},
+		{target: "clone", want: "clone"},
+		// "bat" and "cot" are both one edit from "cat"; the first wins
+		{target: "cat", want: "bat"},
 	}
 	for _, tc := range tcs {
-		got, err := script.ListFiles(tc.testFileName).SHA256Sums().String()
+		got, err := script.Echo(input).NearestLine(tc.target)
 		if err != nil {
 			t.Fatal(err)
 		}
-		if got != tc.want {
-			t.Errorf("%q: want %q, got %q", tc.testFileName, tc.want, got)
+		if tc.want != got {
+			t.Errorf("NearestLine(%q): want %q, got %q", tc.target, tc.want, got)
 		}
 	}
 }
 
-func TestTeeUsesConfiguredStdoutAsDefault(t *testing.T) {
+func TestNormalizeEmailsLowercasesDomainsAndSkipsInvalidAddresses(t *testing.T) {
 	t.Parallel()
-	buf := new(bytes.Buffer)
-	_, err := script.Echo("hello").WithStdout(buf).Tee().String()
+	input := "  alice@example.com\nBob.Smith@Example.COM \nCarol <carol@MAIL.example.org>\nnot an email\n@missing-local.com\ndave@\n\n"
+	want := "alice@example.com\nBob.Smith@example.com\ncarol@mail.example.org\n"
+	got, err := script.Echo(input).NormalizeEmails().String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	want := "hello"
-	got := buf.String()
-	if got != want {
-		t.Errorf("want %q, got %q", want, got)
+	if want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestTeeWritesDataToSuppliedWritersAsWellAsToPipe(t *testing.T) {
+func TestNumberLinesPrefixesLinesWithRightJustifiedNumbers(t *testing.T) {
 	t.Parallel()
-	buf1, buf2 := new(bytes.Buffer), new(bytes.Buffer)
-	got, err := script.Echo("hello world").Tee(buf1, buf2).String()
+	want := "     1\ta\n     2\t\n     3\tc\n"
+	got, err := script.Echo("a\n\nc\n").NumberLines().String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	want := "hello world"
 	if want != got {
-		t.Errorf("want %q on pipe, got %q", want, got)
-	}
-	if want != buf1.String() {
-		t.Errorf("want %q on writer 1, got %q", want, buf1.String())
-	}
-	if want != buf2.String() {
-		t.Errorf("want %q on writer 2, got %q", want, buf2.String())
-	}
-}
-
-func TestExecErrorsWhenTheSpecifiedCommandDoesNotExist(t *testing.T) {
-	t.Parallel()
-	p := script.Exec("doesntexist")
-	p.Wait()
-	if p.Error() == nil {
-		t.Error("want error running non-existent command")
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestExecRunsGoWithNoArgsAndGetsUsageMessagePlusErrorExitStatus2(t *testing.T) {
+func TestNumberLinesFromGrowsFieldWidthForLongNumbers(t *testing.T) {
 	t.Parallel()
-	// We can't make many cross-platform assumptions about what external
-	// commands will be available, but it seems logical that 'go' would be
-	// (though it may not be in the user's path)
-	p := script.Exec("go")
-	output, err := p.String()
-	if err == nil {
-		t.Fatal("want error when command returns a non-zero exit status")
-	}
-	if !strings.Contains(output, "Usage") {
-		t.Fatalf("want output containing the word 'Usage', got %q", output)
+	want := "999999\ta\n1000000\tb\n"
+	got, err := script.Echo("a\nb\n").NumberLinesFrom(999999).String()
+	if err != nil {
+		t.Fatal(err)
 	}
-	want := 2
-	got := p.ExitStatus()
 	if want != got {
-		t.Errorf("want exit status %d, got %d", want, got)
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestExecRunsGoHelpAndGetsUsageMessage(t *testing.T) {
+func TestParseDurationRewritesColumnAsNumberOfUnits(t *testing.T) {
 	t.Parallel()
-	p := script.Exec("go help")
-	if p.Error() != nil {
-		t.Fatal(p.Error())
+	input := "build 1h2m3s ok\ntest 250ms ok\nlint n/a ok\n"
+	want := "build 3723 ok\ntest 0.25 ok\nlint n/a ok\n"
+	got, err := script.Echo(input).ParseDuration(2).String()
+	if err != nil {
+		t.Fatal(err)
 	}
-	output, err := p.String()
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+	got, err = script.Echo("req 2h\n").ParseDurationUnit(2, time.Millisecond).String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !strings.Contains(output, "Usage") {
-		t.Fatalf("want output containing the word 'Usage', got %q", output)
+	if want := "req 7200000\n"; want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestFileOutputsContentsOfSpecifiedFile(t *testing.T) {
+func TestPostPostsToGivenURLUsingPipeAsRequestBody(t *testing.T) {
 	t.Parallel()
-	want := "This is the first line in the file.\nHello, world.\nThis is another line in the file.\n"
-	got, err := script.File("testdata/test.txt").String()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("want HTTP method POST, got %q", r.Method)
+		}
+		want := []byte("request data")
+		got, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal("reading request body", err)
+		}
+		if !cmp.Equal(want, got) {
+			t.Fatal(cmp.Diff(want, string(got)))
+		}
+		fmt.Fprintln(w, "response data")
+	}))
+	defer ts.Close()
+	want := "response data\n"
+	got, err := script.Echo("request data").Post(ts.URL).String()
 	if err != nil {
-		t.Fatal(err)
+		t.Fatalf("unexpected error: %v", err)
 	}
-	if want != got {
+	if !cmp.Equal(want, got) {
 		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestFileErrorsOnNonexistentFile(t *testing.T) {
+func TestPutPatchAndDeleteUseTheirHTTPMethods(t *testing.T) {
 	t.Parallel()
-	p := script.File("doesntexist")
-	if p.Error() == nil {
-		t.Error("want error for non-existent file")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %q %d", r.Method, body, len(r.TransferEncoding))
+	}))
+	defer ts.Close()
+	tcs := []struct {
+		name string
+		pipe *script.Pipe
+		want string
+	}{
+		{"put", script.Echo("data").Put(ts.URL), `PUT "data" 1`},
+		{"patch", script.Echo("data").Patch(ts.URL), `PATCH "data" 1`},
+		{"put source", script.Put(ts.URL), `PUT "" 1`},
+		{"delete", script.Delete(ts.URL), `DELETE "" 0`},
+		{"delete with body", script.Echo("data").Delete(ts.URL), `DELETE "data" 0`},
+	}
+	for _, tc := range tcs {
+		got, err := tc.pipe.String()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: want %q, got %q", tc.name, tc.want, got)
+		}
 	}
 }
 
-func TestFindFiles_ReturnsListOfFiles(t *testing.T) {
+func TestPostJSON_SendsValueEncodedAsJSON(t *testing.T) {
 	t.Parallel()
-	p := script.FindFiles("testdata/multiple_files")
-	if p.Error() != nil {
-		t.Fatal(p.Error())
-	}
-	p.SetError(nil) // else p.String() would be a no-op
-	// Expect result to use this platform's path separator
-	want := filepath.Clean("testdata/multiple_files/1.txt\ntestdata/multiple_files/2.txt\ntestdata/multiple_files/3.tar.zip\n")
-	got, err := p.String()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, r.Method, r.Header.Get("Content-Type"))
+		io.Copy(w, r.Body)
+	}))
+	defer ts.Close()
+	v := struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}{"widget", 3}
+	want := "POST application/json\n{\"name\":\"widget\",\"count\":3}"
+	got, err := script.PostJSON(ts.URL, v).String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !cmp.Equal(want, got) {
-		t.Fatal(cmp.Diff(want, got))
+	if want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestFindFiles_RecursesIntoSubdirectories(t *testing.T) {
+func TestPostJSON_SetsErrorWithoutRequestGivenUnencodableValue(t *testing.T) {
 	t.Parallel()
-	p := script.FindFiles("testdata/multiple_files_with_subdirectory")
-	if p.Error() != nil {
-		t.Fatal(p.Error())
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer ts.Close()
+	_, err := script.PostJSON(ts.URL, make(chan int)).String()
+	if err == nil {
+		t.Error("want error given value that can't be encoded as JSON")
 	}
-	p.SetError(nil) // else p.String() would be a no-op
-	// Expect result to use this platform's path separator
-	want := filepath.Clean("testdata/multiple_files_with_subdirectory/1.txt\ntestdata/multiple_files_with_subdirectory/2.txt\ntestdata/multiple_files_with_subdirectory/3.tar.zip\ntestdata/multiple_files_with_subdirectory/dir/.hidden\ntestdata/multiple_files_with_subdirectory/dir/1.txt\ntestdata/multiple_files_with_subdirectory/dir/2.txt\n")
-	got, err := p.String()
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Errorf("want no request, got %d", n)
+	}
+}
+
+func TestRequireLineCountPassesThroughInputWithinBounds(t *testing.T) {
+	t.Parallel()
+	want := "a\nb\nc"
+	got, err := script.Echo(want).RequireLineCount(2, 3).String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !cmp.Equal(want, got) {
-		t.Fatal(cmp.Diff(want, got))
+	if want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestFindFiles_InNonexistentPathReturnsError(t *testing.T) {
+func TestRequireLineCountSetsErrorGivenTooFewLines(t *testing.T) {
 	t.Parallel()
-	p := script.FindFiles("nonexistent_path")
-	if p.Error() == nil {
-		t.Fatal("want error for nonexistent path")
+	got, err := script.Echo("a\n").RequireLineCount(2, 0).String()
+	if err == nil {
+		t.Fatal("want error given too few lines")
+	}
+	if want := "a\n"; want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestIfExists_ProducesErrorPlusNoOutputForNonexistentFile(t *testing.T) {
+func TestRequireLineCountSetsErrorGivenTooManyLines(t *testing.T) {
 	t.Parallel()
-	want := ""
-	got, err := script.IfExists("testdata/doesntexist").Echo("hello").String()
+	_, err := script.Echo("a\nb\nc\n").RequireLineCount(1, 2).String()
 	if err == nil {
-		t.Fatal("want error")
-	}
-	if want != got {
-		t.Error(cmp.Diff(want, got))
+		t.Fatal("want error given too many lines")
 	}
 }
 
-func TestIfExists_ProducesOutputAndNoErrorWhenFileExists(t *testing.T) {
+func TestRequireLineCountTreatsNonPositiveMaxAsUnbounded(t *testing.T) {
 	t.Parallel()
-	want := "hello"
-	got, err := script.IfExists("testdata/empty.txt").Echo("hello").String()
+	_, err := script.Echo(strings.Repeat("x\n", 1000)).RequireLineCount(1, 0).String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if want != got {
-		t.Error(cmp.Diff(want, got))
-	}
 }
 
-func TestListFiles_OutputsDirectoryContentsGivenDirectoryPath(t *testing.T) {
+func TestRequireNonEmptyPassesThroughNonEmptyInput(t *testing.T) {
 	t.Parallel()
-	want := filepath.Clean("testdata/multiple_files/1.txt\ntestdata/multiple_files/2.txt\ntestdata/multiple_files/3.tar.zip\n")
-	got, err := script.ListFiles("testdata/multiple_files").String()
+	want := "hello\n"
+	got, err := script.Echo(want).RequireNonEmpty("no output").String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if got != want {
-		t.Errorf("Want %q, got %q", want, got)
+	if want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestListFiles_ErrorsOnNonexistentPath(t *testing.T) {
+func TestRequireNonEmptySetsErrorGivenEmptyInput(t *testing.T) {
 	t.Parallel()
-	p := script.ListFiles("nonexistentpath")
-	if p.Error() == nil {
-		t.Error("want error status on listing non-existent path, but got nil")
+	_, err := script.Echo("").RequireNonEmpty("no output").String()
+	if err == nil {
+		t.Fatal("want error given empty input")
+	}
+	if want, got := "no output", err.Error(); want != got {
+		t.Errorf("want error %q, got %q", want, got)
 	}
 }
 
-func TestListFiles_OutputsSingleFileGivenFilePath(t *testing.T) {
+func TestResolveURLResolvesRelativeURLsAgainstBase(t *testing.T) {
 	t.Parallel()
-	got, err := script.ListFiles("testdata/multiple_files/1.txt").String()
+	input := "/foo\n../bar\nbaz?q=1\n\nhttps://other.example/x\n:bad\n"
+	want := "https://example.com/foo\nhttps://example.com/a/bar\nhttps://example.com/a/b/baz?q=1\nhttps://other.example/x\n"
+	got, err := script.Echo(input).ResolveURL("https://example.com/a/b/page.html").String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	want := "testdata/multiple_files/1.txt"
-	if !cmp.Equal(want, got) {
+	if want != got {
 		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestListFiles_OutputsAllFilesMatchingSpecifiedGlobExpression(t *testing.T) {
+func TestReverseOutputsLinesInReverseOrder(t *testing.T) {
 	t.Parallel()
-	want := filepath.Clean("testdata/multiple_files/1.txt\ntestdata/multiple_files/2.txt\n")
-	got, err := script.ListFiles("testdata/multi?le_files/*.txt").String()
+	want := "c\n  b \na\n"
+	got, err := script.Echo("a\n  b \nc").Reverse().String()
 	if err != nil {
 		t.Fatal(err)
 	}
 	if want != got {
-		t.Errorf("Want %q, got %q", want, got)
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestReadAutoCloser_ReadsAllDataFromSourceAndClosesItAutomatically(t *testing.T) {
+func TestReverseHandlesLongLines(t *testing.T) {
 	t.Parallel()
-	want := []byte("hello world")
-	input, err := os.Open("testdata/hello.txt")
-	if err != nil {
-		t.Fatal(err)
-	}
-	acr := script.NewReadAutoCloser(input)
-	got, err := io.ReadAll(acr)
+	got, err := script.Echo(longLine).Reverse().Slice()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !cmp.Equal(want, got) {
-		t.Fatal(cmp.Diff(want, got))
+	if len(got) != 2 || got[0] != "last line" {
+		t.Errorf("wrong result: %.40q", got)
 	}
-	// Go docs: Because ReadAll is defined to read from src until EOF, it
-	// does not treat an EOF from Read as an error to be reported.
-	// _, err = io.ReadAll(acr)
-	_, err = acr.Read(make([]byte, 32))
-	if err == nil {
-		t.Error("input not closed after reading")
+}
+
+func TestRejectRegexp_DropsMatchingLinesFromInput(t *testing.T) {
+	t.Parallel()
+	input := "hello world"
+	tcs := []struct {
+		regex, want string
+	}{
+		{
+			regex: `Hello|line`,
+			want:  "hello world\n",
+		},
+		{
+			regex: `hello|bogus`,
+			want:  "",
+		},
+		{
+			regex: `w.*d`,
+			want:  "",
+		},
+		{
+			regex: "wontmatch",
+			want:  "hello world\n",
+		},
+	}
+	for _, tc := range tcs {
+		got, err := script.Echo(input).RejectRegexp(regexp.MustCompile(tc.regex)).String()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.want != got {
+			t.Error(cmp.Diff(tc.want, got))
+		}
 	}
 }
 
-func TestSliceProducesElementsOfSpecifiedSliceOnePerLine(t *testing.T) {
+func TestRunningMinMaxAppendsRunningExtremes(t *testing.T) {
 	t.Parallel()
-	want := "1\n2\n3\n"
-	got, err := script.Slice([]string{"1", "2", "3"}).String()
+	input := "t0 n/a\nt1 5\nt2 3\nt3 n/a\nt4 8\nt5 4.5\n"
+	want := "t0 n/a\nt1 5 5 5\nt2 3 3 5\nt3 n/a 3 5\nt4 8 3 8\nt5 4.5 3 8\n"
+	got, err := script.Echo(input).RunningMinMax(2).String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !cmp.Equal(want, got) {
+	if want != got {
 		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestStdoutReturnsErrorGivenReadErrorOnPipe(t *testing.T) {
+func TestSampleSeedOutputsNDistinctInputLinesReproducibly(t *testing.T) {
 	t.Parallel()
-	brokenReader := iotest.ErrReader(errors.New("oh no"))
-	_, err := script.NewPipe().WithStdout(io.Discard).
-		WithReader(brokenReader).Stdout()
-	if err == nil {
-		t.Fatal(nil)
+	input := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	first, err := script.Echo(input).SampleSeed(3, 42).Slice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 3 {
+		t.Fatalf("want 3 lines, got %q", first)
+	}
+	seen := map[string]bool{}
+	for _, line := range first {
+		n, err := strconv.Atoi(line)
+		if err != nil || n < 1 || n > 10 || seen[line] {
+			t.Errorf("unexpected sampled line %q in %q", line, first)
+		}
+		seen[line] = true
+	}
+	second, err := script.Echo(input).SampleSeed(3, 42).Slice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(first, second) {
+		t.Error(cmp.Diff(first, second))
 	}
 }
 
-func TestStdoutSendsPipeContentsToConfiguredStandardOutput(t *testing.T) {
+func TestSampleOutputsAllLinesGivenFewerThanN(t *testing.T) {
 	t.Parallel()
-	buf := new(bytes.Buffer)
-	want := "hello world"
-	p := script.File("testdata/hello.txt").WithStdout(buf)
-	wrote, err := p.Stdout()
+	want := "a\nb\n"
+	got, err := script.Echo(want).Sample(5).String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if wrote != len(want) {
-		t.Fatalf("want %d bytes written, got %d", len(want), wrote)
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestSelectColumnsOutputsNamedColumnsInRequestedOrder(t *testing.T) {
+	t.Parallel()
+	input := strings.Join([]string{
+		"id  name   status  age",
+		"1   alice  active  30",
+		"2   bob    gone    41",
+		"3   carol",
+	}, "\n")
+	want := "age name\n30 alice\n41 bob\n"
+	got, err := script.Echo(input).SelectColumns("age", "name").String()
+	if err != nil {
+		t.Fatal(err)
 	}
-	got := buf.String()
 	if want != got {
-		t.Fatalf("want %q, got %q", want, string(got))
+		t.Error(cmp.Diff(want, got))
 	}
-	// _, _ := p.String()
-	if !p.Pipeline.IsClosed() {
-		t.Error("input not closed after reading")
+}
+
+func TestSelectColumnsSetsErrorGivenMissingHeader(t *testing.T) {
+	t.Parallel()
+	_, err := script.Echo("id name\n1 alice\n").SelectColumns("name", "email").String()
+	if err == nil {
+		t.Error("want error given header not present in input")
 	}
 }
 
-func TestAppendFile_AppendsAllItsInputToSpecifiedFile(t *testing.T) {
+func TestSHA256Sums_OutputsCorrectHashForEachSpecifiedFile(t *testing.T) {
 	t.Parallel()
-	orig := "Hello, world"
-	path := t.TempDir() + "/" + t.Name()
-	_, err := script.Echo(orig).WriteFile(path)
+	tcs := []struct {
+		testFileName string
+		want         string
+	}{
+		// To get the checksum run: openssl dgst -sha256 <file_name>
+		{"testdata/sha256Sum.input.txt", "1870478d23b0b4db37735d917f4f0ff9393dd3e52d8b0efa852ab85536ddad8e\n"},
+		{"testdata/hello.txt", "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9\n"},
+		{"testdata/multiple_files", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\ne3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\ne3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\n"},
+	}
+	for _, tc := range tcs {
+		got, err := script.ListFiles(tc.testFileName).SHA256Sums().String()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%q: want %q, got %q", tc.testFileName, tc.want, got)
+		}
+	}
+}
+
+func TestShuffleSeedOutputsPermutationOfInputReproducibly(t *testing.T) {
+	t.Parallel()
+	input := "a\nb\nc\nd\ne\nf\n"
+	first, err := script.Echo(input).ShuffleSeed(7).Slice()
 	if err != nil {
 		t.Fatal(err)
 	}
-	extra := " and goodbye"
-	wrote, err := script.Echo(extra).AppendFile(path)
+	sorted := append([]string(nil), first...)
+	sort.Strings(sorted)
+	want := []string{"a", "b", "c", "d", "e", "f"}
+	if !cmp.Equal(want, sorted) {
+		t.Error(cmp.Diff(want, sorted))
+	}
+	second, err := script.Echo(input).ShuffleSeed(7).Slice()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if int(wrote) != len(extra) {
-		t.Fatalf("want %d bytes written, got %d", len(extra), int(wrote))
+	if !cmp.Equal(first, second) {
+		t.Error(cmp.Diff(first, second))
 	}
-	// check file contains both contents
-	got, err := script.File(path).String()
+}
+
+func TestSkipDropsFirstNLines(t *testing.T) {
+	t.Parallel()
+	input := "header\na\nb\n"
+	tcs := []struct {
+		n    int
+		want string
+	}{
+		{n: 1, want: "a\nb\n"},
+		{n: 3, want: ""},
+		{n: 5, want: ""},
+		{n: 0, want: input},
+		{n: -1, want: input},
+	}
+	for _, tc := range tcs {
+		got, err := script.Echo(input).Skip(tc.n).String()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.want != got {
+			t.Errorf("Skip(%d): %s", tc.n, cmp.Diff(tc.want, got))
+		}
+	}
+}
+
+func TestSkipLastDropsLastNLines(t *testing.T) {
+	t.Parallel()
+	input := "a\nb\nc\ntotal: 3\n"
+	tcs := []struct {
+		n    int
+		want string
+	}{
+		{n: 1, want: "a\nb\nc\n"},
+		{n: 3, want: "a\n"},
+		{n: 4, want: ""},
+		{n: 10, want: ""},
+		{n: 0, want: input},
+		{n: -1, want: input},
+	}
+	for _, tc := range tcs {
+		got, err := script.Echo(input).SkipLast(tc.n).String()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.want != got {
+			t.Errorf("SkipLast(%d): %s", tc.n, cmp.Diff(tc.want, got))
+		}
+	}
+}
+
+func TestSortSortsLinesLexically(t *testing.T) {
+	t.Parallel()
+	want := "apple\nbanana\ncherry\n"
+	got, err := script.Echo("cherry\napple\nbanana\n").Sort().String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if got != orig+extra {
-		t.Errorf("want %q, got %q", orig+extra, got)
+	if want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestAppendFile_ReturnsBytesWrittenAndErrorGivenReadErrorOnPipe(t *testing.T) {
+func TestSortNumericSortsLinesInAscendingNumericalOrder(t *testing.T) {
 	t.Parallel()
-	var want int64 = 1
-	got, err := script.NewPipe().WithReader(partialErrReader{}).AppendFile(t.TempDir() + "/tmp")
-	if err == nil {
-		t.Fatal("want error reading pipe with error status, got nil")
+	input := "10\nfoo\n -3\n1e2\n2.5\nbar\n-10\n"
+	want := "-10\n -3\n2.5\n10\n1e2\nfoo\nbar\n"
+	got, err := script.Echo(input).SortNumeric().String()
+	if err != nil {
+		t.Fatal(err)
 	}
-	if !cmp.Equal(want, got) {
+	if want != got {
 		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestBytesOutputsInputBytesUnchanged(t *testing.T) {
+func TestSortReverseSortsLinesInDescendingNumericalOrder(t *testing.T) {
 	t.Parallel()
-	want := []byte{8, 0, 0, 16}
-	input := bytes.NewReader(want)
-	got, err := script.NewPipe().WithReader(input).Bytes()
+	input := "10\nfoo\n -3\n1e2\n2.5\nbar\n-10\n"
+	want := "1e2\n10\n2.5\n -3\n-10\nfoo\nbar\n"
+	got, err := script.Echo(input).SortReverse().String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !cmp.Equal(want, got) {
+	if want != got {
 		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestBytesReturnsErrorGivenReadErrorOnPipe(t *testing.T) {
+func TestSplitPipeRunsEachChunkThroughSubPipeline(t *testing.T) {
 	t.Parallel()
-	brokenReader := iotest.ErrReader(errors.New("oh no"))
-	_, err := script.NewPipe().WithReader(brokenReader).Bytes()
-	if err == nil {
-		t.Fatal(nil)
+	input := "kind: a\nname: one\n---\nkind: b\nname: two\n"
+	want := "KIND: A\nNAME: ONE\nKIND: B\nNAME: TWO\n"
+	got, err := script.Echo(input).SplitPipe("---\n", func(p *script.Pipe) *script.Pipe {
+		return p.FilterLine(strings.ToUpper)
+	}).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestCountLines_CountsCorrectNumberOfLinesInInput(t *testing.T) {
+func TestSplitPipeProcessesChunksIndependently(t *testing.T) {
 	t.Parallel()
-	want := 3
-	got, err := script.Echo("a\nb\nc").CountLines()
+	input := "a\nb\n---\nc\nd\n---\ne\n---\n"
+	want := "b\nd\ne\n"
+	got, err := script.Echo(input).SplitPipe("---\n", func(p *script.Pipe) *script.Pipe {
+		return p.Last(1)
+	}).String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if got != want {
-		t.Errorf("want %d, got %d", want, got)
+	if want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestCountLines_Counts0LinesInEmptyInput(t *testing.T) {
+func TestSplitPipeSetsErrorGivenEmptyDelimiter(t *testing.T) {
 	t.Parallel()
-	want := 0
-	got, err := script.Echo("").CountLines()
+	p := script.Echo("a\n").SplitPipe("", func(p *script.Pipe) *script.Pipe {
+		return p
+	})
+	p.Wait()
+	if p.Error() == nil {
+		t.Error("want error given empty delimiter")
+	}
+}
+
+func TestSubtractColumnOutputsRowsWhoseColumnIsNotInFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "processed.txt")
+	if err := os.WriteFile(path, []byte("102\n 104 \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	input := "alice 101\nbob 102\ncarol 103\ndave 104\neve\n"
+	want := "alice 101\ncarol 103\neve\n"
+	got, err := script.Echo(input).SubtractColumn(2, path).String()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if got != want {
-		t.Errorf("want %d, got %d", want, got)
+	if want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestCountLines_ReturnsErrorGivenReadErrorOnPipe(t *testing.T) {
+func TestSubtractColumnSetsErrorGivenNonexistentFile(t *testing.T) {
 	t.Parallel()
-	brokenReader := iotest.ErrReader(errors.New("oh no"))
-	_, err := script.NewPipe().WithReader(brokenReader).CountLines()
+	_, err := script.Echo("a 1\n").SubtractColumn(2, "testdata/doesntexist").String()
 	if err == nil {
-		t.Fatal(nil)
+		t.Fatal("want error given nonexistent file")
 	}
 }
 
-func TestSHA256Sum_OutputsCorrectHash(t *testing.T) {
+func TestTemplateRendersEntireInputAsSingleValue(t *testing.T) {
 	t.Parallel()
-	tcs := []struct {
-		name, input, want string
-	}{
-		{
-			name:  "for no data",
-			input: "",
-			want:  "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
-		},
-		{
-			name:  "for short string",
-			input: "hello, world",
-			want:  "09ca7e4eaa6e8ae9c7d261167129184883644d07dfba7cbfbc4c8a2e08360d5b",
-		},
-		{
-			name:  "for string containing newline",
-			input: "The tao that can be told\nis not the eternal Tao",
-			want:  "788542cb92d37f67e187992bdb402fdfb68228a1802947f74c6576e04790a688",
-		},
+	tmpl := "Report ({{len .Lines}} items):\n{{range $i, $line := .Lines}}{{$i}}. {{$line}}\n{{end}}---\n{{.}}"
+	want := "Report (2 items):\n0. alpha\n1. beta\n---\nalpha\nbeta\n"
+	got, err := script.Echo("alpha\nbeta\n").Template(tmpl).String()
+	if err != nil {
+		t.Fatal(err)
 	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
 
-	for _, tc := range tcs {
-		t.Run(tc.name, func(t *testing.T) {
-			got, err := script.Echo(tc.input).SHA256Sum()
-			if err != nil {
-				t.Fatal(err)
-			}
-			if got != tc.want {
-				t.Errorf("want %q, got %q", tc.want, got)
-			}
-		})
+func TestTemplateSetsErrorGivenInvalidTemplate(t *testing.T) {
+	t.Parallel()
+	_, err := script.Echo("data").Template("{{.Missing").String()
+	if err == nil {
+		t.Fatal("want error given unparseable template")
+	}
+	_, err = script.Echo("data").Template("{{.Missing}}").String()
+	if err == nil {
+		t.Fatal("want error given template failing to execute")
+	}
+}
+
+func TestTeeUsesConfiguredStdoutAsDefault(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	_, err := script.Echo("hello").WithStdout(buf).Tee().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "hello"
+	got := buf.String()
+	if got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestTeeWritesDataToSuppliedWritersAsWellAsToPipe(t *testing.T) {
+	t.Parallel()
+	buf1, buf2 := new(bytes.Buffer), new(bytes.Buffer)
+	got, err := script.Echo("hello world").Tee(buf1, buf2).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "hello world"
+	if want != got {
+		t.Errorf("want %q on pipe, got %q", want, got)
+	}
+	if want != buf1.String() {
+		t.Errorf("want %q on writer 1, got %q", want, buf1.String())
+	}
+	if want != buf2.String() {
+		t.Errorf("want %q on writer 2, got %q", want, buf2.String())
+	}
+}
+
+func TestTeeFileWritesDataToFilesAsWellAsToPipe(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")}
+	if err := os.WriteFile(paths[0], []byte("old data to truncate\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want := "hello\n"
+	got, err := script.Echo(want).TeeFile(paths...).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+	for _, path := range paths {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want != string(got) {
+			t.Errorf("%s: %s", path, cmp.Diff(want, string(got)))
+		}
+	}
+}
+
+func TestTeeAppendFileAppendsDataToFiles(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "a.log")
+	if err := os.WriteFile(path, []byte("first\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := script.Echo("second\n").TeeAppendFile(path).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "first\nsecond\n"; want != string(got) {
+		t.Error(cmp.Diff(want, string(got)))
+	}
+}
+
+func TestTeeFileSetsErrorGivenUnopenableFile(t *testing.T) {
+	t.Parallel()
+	_, err := script.Echo("hello\n").TeeFile("doesntexist/a.log").String()
+	if err == nil {
+		t.Fatal("want error given file in nonexistent directory")
+	}
+}
+
+func TestTeeLineCountReportsFinalLineCountAndPassesDataThrough(t *testing.T) {
+	t.Parallel()
+	input := strings.Repeat("line\n", 2500) + "no newline"
+	var counts []int
+	got, err := script.Echo(input).TeeLineCount(func(n int) {
+		counts = append(counts, n)
+	}).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if input != got {
+		t.Error("want input passed through unchanged")
+	}
+	want := []int{1000, 2000, 2501}
+	if !cmp.Equal(want, counts) {
+		t.Error(cmp.Diff(want, counts))
+	}
+}
+
+func TestExecErrorsWhenTheSpecifiedCommandDoesNotExist(t *testing.T) {
+	t.Parallel()
+	p := script.Exec("doesntexist")
+	p.Wait()
+	if p.Error() == nil {
+		t.Error("want error running non-existent command")
+	}
+}
+
+func TestExecRunsGoWithNoArgsAndGetsUsageMessagePlusErrorExitStatus2(t *testing.T) {
+	t.Parallel()
+	// We can't make many cross-platform assumptions about what external
+	// commands will be available, but it seems logical that 'go' would be
+	// (though it may not be in the user's path)
+	p := script.Exec("go")
+	output, err := p.String()
+	if err == nil {
+		t.Fatal("want error when command returns a non-zero exit status")
+	}
+	if !strings.Contains(output, "Usage") {
+		t.Fatalf("want output containing the word 'Usage', got %q", output)
+	}
+	want := 2
+	got := p.ExitStatus()
+	if want != got {
+		t.Errorf("want exit status %d, got %d", want, got)
+	}
+}
+
+func TestExecRunsGoHelpAndGetsUsageMessage(t *testing.T) {
+	t.Parallel()
+	p := script.Exec("go help")
+	if p.Error() != nil {
+		t.Fatal(p.Error())
+	}
+	output, err := p.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "Usage") {
+		t.Fatalf("want output containing the word 'Usage', got %q", output)
+	}
+}
+
+func TestFileOutputsContentsOfSpecifiedFile(t *testing.T) {
+	t.Parallel()
+	want := "This is the first line in the file.\nHello, world.\nThis is another line in the file.\n"
+	got, err := script.File("testdata/test.txt").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestFileErrorsOnNonexistentFile(t *testing.T) {
+	t.Parallel()
+	p := script.File("doesntexist")
+	if p.Error() == nil {
+		t.Error("want error for non-existent file")
+	}
+}
+
+func TestFollowFileContext_ProducesAppendedDataUntilCancelled(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "growing.log")
+	err := os.WriteFile(path, []byte("existing\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := script.FollowFileContext(ctx, path)
+	r := bufio.NewReader(p)
+	readLine := func(want string) {
+		t.Helper()
+		got, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want != got {
+			t.Errorf("want %q, got %q", want, got)
+		}
+	}
+	readLine("existing\n")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString("appended\n"); err != nil {
+		t.Fatal(err)
+	}
+	readLine("appended\n")
+	// Truncating the file should start reading from the beginning again
+	if err := os.WriteFile(path, []byte("new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	readLine("new\n")
+	cancel()
+	p.Wait()
+	if !errors.Is(p.Error(), context.Canceled) {
+		t.Errorf("want context.Canceled error, got %v", p.Error())
+	}
+}
+
+func TestFollowFileContext_ErrorsOnNonexistentFile(t *testing.T) {
+	t.Parallel()
+	p := script.FollowFileContext(context.Background(), "doesntexist")
+	p.Wait()
+	if p.Error() == nil {
+		t.Error("want error following non-existent file")
+	}
+}
+
+func TestFindFiles_ReturnsListOfFiles(t *testing.T) {
+	t.Parallel()
+	p := script.FindFiles("testdata/multiple_files")
+	if p.Error() != nil {
+		t.Fatal(p.Error())
+	}
+	p.SetError(nil) // else p.String() would be a no-op
+	// Expect result to use this platform's path separator
+	want := filepath.Clean("testdata/multiple_files/1.txt\ntestdata/multiple_files/2.txt\ntestdata/multiple_files/3.tar.zip\n")
+	got, err := p.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Fatal(cmp.Diff(want, got))
+	}
+}
+
+func TestFindFiles_RecursesIntoSubdirectories(t *testing.T) {
+	t.Parallel()
+	p := script.FindFiles("testdata/multiple_files_with_subdirectory")
+	if p.Error() != nil {
+		t.Fatal(p.Error())
+	}
+	p.SetError(nil) // else p.String() would be a no-op
+	// Expect result to use this platform's path separator
+	want := filepath.Clean("testdata/multiple_files_with_subdirectory/1.txt\ntestdata/multiple_files_with_subdirectory/2.txt\ntestdata/multiple_files_with_subdirectory/3.tar.zip\ntestdata/multiple_files_with_subdirectory/dir/.hidden\ntestdata/multiple_files_with_subdirectory/dir/1.txt\ntestdata/multiple_files_with_subdirectory/dir/2.txt\n")
+	got, err := p.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Fatal(cmp.Diff(want, got))
+	}
+}
+
+func TestFindFiles_InNonexistentPathReturnsError(t *testing.T) {
+	t.Parallel()
+	p := script.FindFiles("nonexistent_path")
+	if p.Error() == nil {
+		t.Fatal("want error for nonexistent path")
+	}
+}
+
+func TestFindFilesDepthOutputsOnlyFilesUpToMaxDepth(t *testing.T) {
+	t.Parallel()
+	want := filepath.Clean("testdata/multiple_files_with_subdirectory/1.txt\ntestdata/multiple_files_with_subdirectory/2.txt\ntestdata/multiple_files_with_subdirectory/3.tar.zip\n")
+	got, err := script.FindFilesDepth("testdata/multiple_files_with_subdirectory", 0).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+	want = filepath.Clean("testdata/multiple_files_with_subdirectory/1.txt\ntestdata/multiple_files_with_subdirectory/2.txt\ntestdata/multiple_files_with_subdirectory/3.tar.zip\ntestdata/multiple_files_with_subdirectory/dir/.hidden\ntestdata/multiple_files_with_subdirectory/dir/1.txt\ntestdata/multiple_files_with_subdirectory/dir/2.txt\n")
+	got, err = script.FindFilesDepth("testdata/multiple_files_with_subdirectory", 1).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestFindFilesInfoOutputsPathSizeModeAndModTime(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.txt")
+	if err := os.WriteFile(path, []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("%s\t6\t%s\t%s\n", path, info.Mode(), modTime.Local().Format(time.RFC3339))
+	got, err := script.FindFilesInfo(dir).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestFindFilesMatchOutputsOnlyFilesWhoseNameMatchesPattern(t *testing.T) {
+	t.Parallel()
+	want := filepath.Clean("testdata/multiple_files_with_subdirectory/1.txt\ntestdata/multiple_files_with_subdirectory/2.txt\ntestdata/multiple_files_with_subdirectory/dir/1.txt\ntestdata/multiple_files_with_subdirectory/dir/2.txt\n")
+	got, err := script.FindFilesMatch("testdata/multiple_files_with_subdirectory", "*.txt").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Fatal(cmp.Diff(want, got))
+	}
+}
+
+func TestFindFilesMatchReturnsErrorGivenMalformedPattern(t *testing.T) {
+	t.Parallel()
+	p := script.FindFilesMatch("testdata/multiple_files", "[")
+	if p.Error() == nil {
+		t.Fatal("want error for malformed pattern")
+	}
+}
+
+func TestFindFilesMatchReturnsErrorGivenNonexistentPath(t *testing.T) {
+	t.Parallel()
+	p := script.FindFilesMatch("nonexistent_path", "*")
+	if p.Error() == nil {
+		t.Fatal("want error for nonexistent path")
+	}
+}
+
+func TestFindFilesRegexpOutputsOnlyFilesWhosePathMatches(t *testing.T) {
+	t.Parallel()
+	want := filepath.Clean("testdata/multiple_files_with_subdirectory/dir/.hidden\ntestdata/multiple_files_with_subdirectory/dir/1.txt\ntestdata/multiple_files_with_subdirectory/dir/2.txt\n")
+	re := regexp.MustCompile(`dir[/\\]`)
+	got, err := script.FindFilesRegexp("testdata/multiple_files_with_subdirectory", re).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Fatal(cmp.Diff(want, got))
+	}
+}
+
+func TestIfExists_ProducesErrorPlusNoOutputForNonexistentFile(t *testing.T) {
+	t.Parallel()
+	want := ""
+	got, err := script.IfExists("testdata/doesntexist").Echo("hello").String()
+	if err == nil {
+		t.Fatal("want error")
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestIfExists_ProducesOutputAndNoErrorWhenFileExists(t *testing.T) {
+	t.Parallel()
+	want := "hello"
+	got, err := script.IfExists("testdata/empty.txt").Echo("hello").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestLinesToJSONEscapesLinesIntoStringArray(t *testing.T) {
+	t.Parallel()
+	want := `["plain","say \"hi\"","back\\slash","tab\there","<b>"]` + "\n"
+	got, err := script.Echo("plain\nsay \"hi\"\nback\\slash\ntab\there\n<b>\n").LinesToJSON().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestLinesToJSONOutputsEmptyArrayGivenNoInput(t *testing.T) {
+	t.Parallel()
+	want := "[]\n"
+	got, err := script.Echo("").LinesToJSON().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestListFiles_OutputsDirectoryContentsGivenDirectoryPath(t *testing.T) {
+	t.Parallel()
+	want := filepath.Clean("testdata/multiple_files/1.txt\ntestdata/multiple_files/2.txt\ntestdata/multiple_files/3.tar.zip\n")
+	got, err := script.ListFiles("testdata/multiple_files").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("Want %q, got %q", want, got)
+	}
+}
+
+func TestListFiles_ErrorsOnNonexistentPath(t *testing.T) {
+	t.Parallel()
+	p := script.ListFiles("nonexistentpath")
+	if p.Error() == nil {
+		t.Error("want error status on listing non-existent path, but got nil")
+	}
+}
+
+func TestListFiles_OutputsSingleFileGivenFilePath(t *testing.T) {
+	t.Parallel()
+	got, err := script.ListFiles("testdata/multiple_files/1.txt").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "testdata/multiple_files/1.txt"
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestListFiles_OutputsAllFilesMatchingSpecifiedGlobExpression(t *testing.T) {
+	t.Parallel()
+	want := filepath.Clean("testdata/multiple_files/1.txt\ntestdata/multiple_files/2.txt\n")
+	got, err := script.ListFiles("testdata/multi?le_files/*.txt").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Errorf("Want %q, got %q", want, got)
+	}
+}
+
+func TestListFilesGlobMatchesRecursiveWildcard(t *testing.T) {
+	t.Parallel()
+	want := filepath.Clean("testdata/multiple_files_with_subdirectory/1.txt\ntestdata/multiple_files_with_subdirectory/2.txt\ntestdata/multiple_files_with_subdirectory/dir/1.txt\ntestdata/multiple_files_with_subdirectory/dir/2.txt\n")
+	got, err := script.ListFilesGlob("testdata/multiple_files_with_subdirectory/**/*.txt").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestListFilesGlobMatchesSingleLevelWildcard(t *testing.T) {
+	t.Parallel()
+	want := filepath.Clean("testdata/multiple_files_with_subdirectory/dir/1.txt\n")
+	got, err := script.ListFilesGlob("testdata/*/dir/1.*").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestListFilesGlobListsDirectoryGivenPathWithoutWildcards(t *testing.T) {
+	t.Parallel()
+	want := filepath.Clean("testdata/multiple_files/1.txt\ntestdata/multiple_files/2.txt\ntestdata/multiple_files/3.tar.zip\n")
+	got, err := script.ListFilesGlob("testdata/multiple_files").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestListFilesGlobErrorsGivenMalformedPattern(t *testing.T) {
+	t.Parallel()
+	p := script.ListFilesGlob("testdata/**/[")
+	if p.Error() == nil {
+		t.Fatal("want error for malformed pattern")
+	}
+}
+
+func TestReadAutoCloser_ReadsAllDataFromSourceAndClosesItAutomatically(t *testing.T) {
+	t.Parallel()
+	want := []byte("hello world")
+	input, err := os.Open("testdata/hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	acr := script.NewReadAutoCloser(input)
+	got, err := io.ReadAll(acr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Fatal(cmp.Diff(want, got))
+	}
+	// Go docs: Because ReadAll is defined to read from src until EOF, it
+	// does not treat an EOF from Read as an error to be reported.
+	// _, err = io.ReadAll(acr)
+	_, err = acr.Read(make([]byte, 32))
+	if err == nil {
+		t.Error("input not closed after reading")
+	}
+}
+
+func TestSliceProducesElementsOfSpecifiedSliceOnePerLine(t *testing.T) {
+	t.Parallel()
+	want := "1\n2\n3\n"
+	got, err := script.Slice([]string{"1", "2", "3"}).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestStdoutReturnsErrorGivenReadErrorOnPipe(t *testing.T) {
+	t.Parallel()
+	brokenReader := iotest.ErrReader(errors.New("oh no"))
+	_, err := script.NewPipe().WithStdout(io.Discard).
+		WithReader(brokenReader).Stdout()
+	if err == nil {
+		t.Fatal(nil)
+	}
+}
+
+func TestStdoutSendsPipeContentsToConfiguredStandardOutput(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	want := "hello world"
+	p := script.File("testdata/hello.txt").WithStdout(buf)
+	wrote, err := p.Stdout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrote != len(want) {
+		t.Fatalf("want %d bytes written, got %d", len(want), wrote)
+	}
+	got := buf.String()
+	if want != got {
+		t.Fatalf("want %q, got %q", want, string(got))
+	}
+	// _, _ := p.String()
+	if !p.Pipeline.IsClosed() {
+		t.Error("input not closed after reading")
+	}
+}
+
+func TestWrittenReportsBytesCopiedByLastStdoutCall(t *testing.T) {
+	t.Parallel()
+	p := script.Echo("hello world\n").WithStdout(io.Discard)
+	if got := p.Written(); got != 0 {
+		t.Errorf("want 0 bytes written before Stdout, got %d", got)
+	}
+	_, err := p.Stdout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Written(); got != 12 {
+		t.Errorf("want 12 bytes written, got %d", got)
+	}
+}
+
+func TestAppendFile_AppendsAllItsInputToSpecifiedFile(t *testing.T) {
+	t.Parallel()
+	orig := "Hello, world"
+	path := t.TempDir() + "/" + t.Name()
+	_, err := script.Echo(orig).WriteFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra := " and goodbye"
+	wrote, err := script.Echo(extra).AppendFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(wrote) != len(extra) {
+		t.Fatalf("want %d bytes written, got %d", len(extra), int(wrote))
+	}
+	// check file contains both contents
+	got, err := script.File(path).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != orig+extra {
+		t.Errorf("want %q, got %q", orig+extra, got)
+	}
+}
+
+func TestAppendFile_ReturnsBytesWrittenAndErrorGivenReadErrorOnPipe(t *testing.T) {
+	t.Parallel()
+	var want int64 = 1
+	got, err := script.NewPipe().WithReader(partialErrReader{}).AppendFile(t.TempDir() + "/tmp")
+	if err == nil {
+		t.Fatal("want error reading pipe with error status, got nil")
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestBytesOutputsInputBytesUnchanged(t *testing.T) {
+	t.Parallel()
+	want := []byte{8, 0, 0, 16}
+	input := bytes.NewReader(want)
+	got, err := script.NewPipe().WithReader(input).Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestBytesReturnsErrorGivenReadErrorOnPipe(t *testing.T) {
+	t.Parallel()
+	brokenReader := iotest.ErrReader(errors.New("oh no"))
+	_, err := script.NewPipe().WithReader(brokenReader).Bytes()
+	if err == nil {
+		t.Fatal(nil)
+	}
+}
+
+func TestConvertUnitsConvertsColumnBetweenUnitsOfTheSameKind(t *testing.T) {
+	t.Parallel()
+	tcs := []struct {
+		input, from, to, want string
+	}{
+		{"disk  2\tMiB\n", "MiB", "kB", "disk  2097.152\tMiB\n"},
+		{"link 1 B\n", "B", "bit", "link 8 B\n"},
+		{"req 1500 ms\nreq n/a\nshort\n", "ms", "s", "req 1.5 ms\nreq n/a\nshort\n"},
+		{"london 100\nparis -40\n", "C", "F", "london 212\nparis -40\n"},
+	}
+	for _, tc := range tcs {
+		got, err := script.Echo(tc.input).ConvertUnits(2, tc.from, tc.to).String()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.want != got {
+			t.Errorf("%s to %s: %s", tc.from, tc.to, cmp.Diff(tc.want, got))
+		}
+	}
+}
+
+func TestConvertUnitsSetsErrorGivenUnknownOrIncompatibleUnits(t *testing.T) {
+	t.Parallel()
+	for _, units := range [][2]string{{"furlong", "m"}, {"m", "parsec"}, {"s", "kg"}} {
+		_, err := script.Echo("x 1\n").ConvertUnits(2, units[0], units[1]).String()
+		if err == nil {
+			t.Errorf("want error converting %s to %s", units[0], units[1])
+		}
+	}
+}
+
+func TestCountLines_CountsCorrectNumberOfLinesInInput(t *testing.T) {
+	t.Parallel()
+	want := 3
+	got, err := script.Echo("a\nb\nc").CountLines()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("want %d, got %d", want, got)
+	}
+}
+
+func TestCountLines_Counts0LinesInEmptyInput(t *testing.T) {
+	t.Parallel()
+	want := 0
+	got, err := script.Echo("").CountLines()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("want %d, got %d", want, got)
+	}
+}
+
+func TestCountLines_ReturnsErrorGivenReadErrorOnPipe(t *testing.T) {
+	t.Parallel()
+	brokenReader := iotest.ErrReader(errors.New("oh no"))
+	_, err := script.NewPipe().WithReader(brokenReader).CountLines()
+	if err == nil {
+		t.Fatal(nil)
+	}
+}
+
+func TestMD5SHA1SHA512Sum_OutputCorrectHashes(t *testing.T) {
+	t.Parallel()
+	tcs := []struct {
+		name string
+		sum  func(*script.Pipe) (string, error)
+		want string
+	}{
+		{"MD5", (*script.Pipe).MD5Sum, "e4d7f1b4ed2e42d15898f4b27b019da4"},
+		{"SHA1", (*script.Pipe).SHA1Sum, "b7e23ec29af22b0b4e41da31e868d57226121c84"},
+		{"SHA512", (*script.Pipe).SHA512Sum, "8710339dcb6814d0d9d2290ef422285c9322b7163951f9a0ca8f883d3305286f44139aa374848e4174f5aada663027e4548637b6d19894aec4fb6c46a139fbf9"},
+	}
+	for _, tc := range tcs {
+		got, err := tc.sum(script.Echo("hello, world"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: want %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestMD5SHA1SHA512Sums_OutputCorrectHashForEachSpecifiedFile(t *testing.T) {
+	t.Parallel()
+	tcs := []struct {
+		name string
+		sums func(*script.Pipe) *script.Pipe
+		want string
+	}{
+		// To get the checksums run: md5sum, sha1sum or sha512sum <file_name>
+		{"MD5", (*script.Pipe).MD5Sums, "5eb63bbbe01eeed093cb22bb8f5acdc3\n"},
+		{"SHA1", (*script.Pipe).SHA1Sums, "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed\n"},
+		{"SHA512", (*script.Pipe).SHA512Sums, "309ecc489c12d6eb4cc40f50c902f2b4d0ed77ee511a7c7a9bcd3ca86d4cd86f989dd35bc5ff499670da34255b45b0cfd830e81f605dcf7dc5542e93ae9cd76f\n"},
+	}
+	for _, tc := range tcs {
+		got, err := tc.sums(script.Echo("testdata/hello.txt\ndoesntexist\n")).String()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: want %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestContentHashReturnsSHA256OfInput(t *testing.T) {
+	t.Parallel()
+	want := "09ca7e4eaa6e8ae9c7d261167129184883644d07dfba7cbfbc4c8a2e08360d5b"
+	got, err := script.Echo("hello, world").ContentHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestHashChangedReportsWhetherContentChangedSincePreviousRun(t *testing.T) {
+	t.Parallel()
+	stateFile := filepath.Join(t.TempDir(), "hash")
+	for i, tc := range []struct {
+		input string
+		want  bool
+	}{
+		{"hello", true},
+		{"hello", false},
+		{"goodbye", true},
+		{"goodbye", false},
+	} {
+		got, err := script.Echo(tc.input).HashChanged(stateFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.want != got {
+			t.Errorf("run %d with %q: want changed %t, got %t", i+1, tc.input, tc.want, got)
+		}
+	}
+}
+
+func TestSHA256Sum_OutputsCorrectHash(t *testing.T) {
+	t.Parallel()
+	tcs := []struct {
+		name, input, want string
+	}{
+		{
+			name:  "for no data",
+			input: "",
+			want:  "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		},
+		{
+			name:  "for short string",
+			input: "hello, world",
+			want:  "09ca7e4eaa6e8ae9c7d261167129184883644d07dfba7cbfbc4c8a2e08360d5b",
+		},
+		{
+			name:  "for string containing newline",
+			input: "The tao that can be told\nis not the eternal Tao",
+			want:  "788542cb92d37f67e187992bdb402fdfb68228a1802947f74c6576e04790a688",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := script.Echo(tc.input).SHA256Sum()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSHA256Sum_ReturnsErrorGivenReadErrorOnPipe(t *testing.T) {
+	t.Parallel()
+	brokenReader := iotest.ErrReader(errors.New("oh no"))
+	_, err := script.NewPipe().WithReader(brokenReader).SHA256Sum()
+	if err == nil {
+		t.Fatal(nil)
+	}
+}
+
+func TestSliceSink_ReturnsErrorGivenReadErrorOnPipe(t *testing.T) {
+	t.Parallel()
+	brokenReader := iotest.ErrReader(errors.New("oh no"))
+	_, err := script.NewPipe().WithReader(brokenReader).Slice()
+	if err == nil {
+		t.Fatal(nil)
+	}
+}
+
+func TestSliceSink_(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		pipe *script.Pipe
+		want []string
+	}{
+		{
+			name: "returns three elements for three lines of input",
+			pipe: script.Echo("testdata/multiple_files/1.txt\ntestdata/multiple_files/2.txt\ntestdata/multiple_files/3.tar.zip\n"),
+			want: []string{
+				"testdata/multiple_files/1.txt",
+				"testdata/multiple_files/2.txt",
+				"testdata/multiple_files/3.tar.zip",
+			},
+		},
+		{
+			name: "returns an empty slice given empty input",
+			pipe: script.Echo(""),
+			want: []string{},
+		},
+		{
+			name: "returns one empty string given input containing a single newline",
+			pipe: script.Echo("\n"),
+			want: []string{""},
+		},
+		{
+			name: "returns an empty string for each empty input line",
+			pipe: script.Echo("testdata/multiple_files/1.txt\n\ntestdata/multiple_files/3.tar.zip"),
+			want: []string{
+				"testdata/multiple_files/1.txt",
+				"",
+				"testdata/multiple_files/3.tar.zip",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.pipe
+			got, err := p.Slice()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(tt.want, got) {
+				t.Error(cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestStringOutputsInputStringUnchanged(t *testing.T) {
+	t.Parallel()
+	want := "hello, world"
+	got, err := script.Echo(want).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestStringReturnsErrorGivenReadErrorOnPipe(t *testing.T) {
+	t.Parallel()
+	brokenReader := iotest.ErrReader(errors.New("oh no"))
+	_, err := script.NewPipe().WithReader(brokenReader).String()
+	if err == nil {
+		t.Fatal(nil)
+	}
+}
+
+func TestToMarkdownTableProducesAlignedTableWithHeaderSeparator(t *testing.T) {
+	t.Parallel()
+	input := "NAME STATUS AGE\nweb-1 Running 3d\ndb Pending\n\ncache|x Running 12h\n"
+	want := "" +
+		"| NAME     | STATUS  | AGE |\n" +
+		"| -------- | ------- | --- |\n" +
+		"| web-1    | Running | 3d  |\n" +
+		"| db       | Pending |     |\n" +
+		"| cache\\|x | Running | 12h |\n"
+	got, err := script.Echo(input).ToMarkdownTable().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestToMarkdownTableDelimSplitsOnDelimiter(t *testing.T) {
+	t.Parallel()
+	input := "id, full name\n1, Ada Lovelace\n"
+	want := "" +
+		"| id  | full name    |\n" +
+		"| --- | ------------ |\n" +
+		"| 1   | Ada Lovelace |\n"
+	got, err := script.Echo(input).ToMarkdownTableDelim(",").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestTrimSpaceRemovesLeadingAndTrailingWhiteSpaceFromEachLine(t *testing.T) {
+	t.Parallel()
+	want := "a b\nc\n\n"
+	got, err := script.Echo("  a b \t\n\tc\n   \n").TrimSpace().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestTrimPrefixAndTrimSuffixRemovePrefixAndSuffixFromEachLine(t *testing.T) {
+	t.Parallel()
+	want := "one\ntwo\nthree\n"
+	got, err := script.Echo("- one;\n- two\nthree;\n").TrimPrefix("- ").TrimSuffix(";").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestTruncateLimitsOutputToNBytes(t *testing.T) {
+	t.Parallel()
+	tcs := []struct {
+		n    int64
+		want string
+	}{
+		{-1, ""},
+		{0, ""},
+		{7, "hello, "},
+		{100, "hello, world\n"},
+	}
+	for _, tc := range tcs {
+		got, err := script.Echo("hello, world\n").Truncate(tc.n).String()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.want != got {
+			t.Errorf("%d: want %q, got %q", tc.n, tc.want, got)
+		}
+	}
+}
+
+func TestTruncateDoesNotConsumeUnnecessaryData(t *testing.T) {
+	t.Parallel()
+	r := strings.NewReader(strings.Repeat("line\n", 10000))
+	got, err := script.NewPipe().WithReader(r).Truncate(3).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "lin" {
+		t.Errorf("want output %q, got %q", "lin", got)
+	}
+	if r.Len() == 0 {
+		t.Errorf("no data left in reader")
+	}
+}
+
+func TestUniqDropsAdjacentDuplicateLines(t *testing.T) {
+	t.Parallel()
+	want := "a\nb\na\nc\n"
+	got, err := script.Echo("a\na\nb\na\na\na\nc\nc").Uniq().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestUniqCountPrefixesEachRunWithItsLength(t *testing.T) {
+	t.Parallel()
+	want := "      2 a\n      1 b\n      3 a\n      2 c\n"
+	got, err := script.Echo("a\na\nb\na\na\na\nc\nc").UniqCount().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestUniqProducesNoOutputGivenEmptyInput(t *testing.T) {
+	t.Parallel()
+	got, err := script.Echo("").Uniq().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "" {
+		t.Errorf("want no output, got %q", got)
+	}
+}
+
+func TestWaitReadsPipeSourceToCompletion(t *testing.T) {
+	t.Parallel()
+	source := bytes.NewBufferString("hello")
+	script.NewPipe().WithReader(source).FilterLine(strings.ToUpper).Wait()
+	if source.Len() > 0 {
+		t.Errorf("incomplete read: %d bytes of input remaining: %q", source.Len(), source.String())
+	}
+}
+
+func TestWhereColumnOutputsHeaderAndRowsMatchingPredicate(t *testing.T) {
+	t.Parallel()
+	input := strings.Join([]string{
+		"id  name   status",
+		"1   alice  active",
+		"2   bob    gone",
+		"3   carol  active",
+		"4   dave",
+	}, "\n")
+	want := "id  name   status\n1   alice  active\n3   carol  active\n"
+	isActive := func(s string) bool { return s == "active" }
+	got, err := script.Echo(input).WhereColumn("status", isActive).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+	got, err = script.Echo(input).WhereColumn("status", isActive).SelectColumns("name").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "name\nalice\ncarol\n"; want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestSHA256Sum_ReturnsErrorGivenReadErrorOnPipe(t *testing.T) {
+func TestWhereColumnSetsErrorGivenMissingHeader(t *testing.T) {
 	t.Parallel()
-	brokenReader := iotest.ErrReader(errors.New("oh no"))
-	_, err := script.NewPipe().WithReader(brokenReader).SHA256Sum()
+	_, err := script.Echo("id name\n1 alice\n").WhereColumn("status", func(string) bool { return true }).String()
 	if err == nil {
-		t.Fatal(nil)
+		t.Error("want error given header not present in input")
 	}
 }
 
-func TestSliceSink_ReturnsErrorGivenReadErrorOnPipe(t *testing.T) {
+func TestWithOffsetsPrefixesLinesWithStartingByteOffset(t *testing.T) {
 	t.Parallel()
-	brokenReader := iotest.ErrReader(errors.New("oh no"))
-	_, err := script.NewPipe().WithReader(brokenReader).Slice()
-	if err == nil {
-		t.Fatal(nil)
+	input := "alpha\n\nbeta\r\ngamma"
+	want := "0\talpha\n6\t\n7\tbeta\n13\tgamma\n"
+	got, err := script.Echo(input).WithOffsets().String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestSliceSink_(t *testing.T) {
+func TestWordFreqCountsWordsInDescendingOrder(t *testing.T) {
 	t.Parallel()
-	tests := []struct {
-		name string
-		pipe *script.Pipe
-		want []string
-	}{
-		{
-			name: "returns three elements for three lines of input",
-			pipe: script.Echo("testdata/multiple_files/1.txt\ntestdata/multiple_files/2.txt\ntestdata/multiple_files/3.tar.zip\n"),
-			want: []string{
-				"testdata/multiple_files/1.txt",
-				"testdata/multiple_files/2.txt",
-				"testdata/multiple_files/3.tar.zip",
-			},
-		},
-		{
-			name: "returns an empty slice given empty input",
-			pipe: script.Echo(""),
-			want: []string{},
-		},
-		{
-			name: "returns one empty string given input containing a single newline",
-			pipe: script.Echo("\n"),
-			want: []string{""},
-		},
-		{
-			name: "returns an empty string for each empty input line",
-			pipe: script.Echo("testdata/multiple_files/1.txt\n\ntestdata/multiple_files/3.tar.zip"),
-			want: []string{
-				"testdata/multiple_files/1.txt",
-				"",
-				"testdata/multiple_files/3.tar.zip",
-			},
-		},
+	input := "the cat sat on the mat\nthe cat ran\n"
+	want := "3 the\n2 cat\n1 mat\n1 on\n1 ran\n1 sat\n"
+	got, err := script.Echo(input).WordFreq().String()
+	if err != nil {
+		t.Fatal(err)
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			p := tt.pipe
-			got, err := p.Slice()
-			if err != nil {
-				t.Fatal(err)
-			}
-			if !cmp.Equal(tt.want, got) {
-				t.Error(cmp.Diff(tt.want, got))
-			}
-		})
+	if want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestStringOutputsInputStringUnchanged(t *testing.T) {
+func TestWordFreqWithFoldsCaseAndTrimsPunctuation(t *testing.T) {
 	t.Parallel()
-	want := "hello, world"
-	got, err := script.Echo(want).String()
+	input := "The cat, the hat. THE end -- don't stop!"
+	got, err := script.Echo(input).WordFreqWith(true, true).First(2).String()
 	if err != nil {
 		t.Fatal(err)
 	}
+	want := "3 the\n1 cat\n"
 	if want != got {
 		t.Error(cmp.Diff(want, got))
 	}
+	got, err = script.Echo(input).WordFreqWith(true, true).Match("don").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "1 don't\n" {
+		t.Errorf("want internal punctuation kept, got %q", got)
+	}
 }
 
-func TestStringReturnsErrorGivenReadErrorOnPipe(t *testing.T) {
+func TestWrapOnBreaksLongLinesAfterLastSeparatorWithinLimit(t *testing.T) {
 	t.Parallel()
-	brokenReader := iotest.ErrReader(errors.New("oh no"))
-	_, err := script.NewPipe().WithReader(brokenReader).String()
-	if err == nil {
-		t.Fatal(nil)
+	input := "short\nalpha, beta, gamma, delta, epsilon, zeta\nunbreakable-token, x\n"
+	want := "short\nalpha, beta,\ngamma, delta,\nepsilon, zeta\nunbreakable-token,\nx\n"
+	got, err := script.Echo(input).WrapOn(14, ",").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
 	}
 }
 
-func TestWaitReadsPipeSourceToCompletion(t *testing.T) {
+func TestWrapOnSetsErrorGivenEmptySeparator(t *testing.T) {
 	t.Parallel()
-	source := bytes.NewBufferString("hello")
-	script.NewPipe().WithReader(source).FilterLine(strings.ToUpper).Wait()
-	if source.Len() > 0 {
-		t.Errorf("incomplete read: %d bytes of input remaining: %q", source.Len(), source.String())
+	_, err := script.Echo("a b\n").WrapOn(1, "").String()
+	if err == nil {
+		t.Fatal("want error given empty separator")
 	}
 }
 
@@ -1713,6 +4084,168 @@ func TestWriteFile_TruncatesExistingFile(t *testing.T) {
 	}
 }
 
+func TestWriteFileAllCreatesParentDirectories(t *testing.T) {
+	t.Parallel()
+	want := "Hello, world"
+	path := filepath.Join(t.TempDir(), "out", "reports", "2024", "summary.txt")
+	wrote, err := script.Echo(want).WriteFileAll(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(wrote) != len(want) {
+		t.Fatalf("want %d bytes written, got %d", len(want), int(wrote))
+	}
+	got, err := script.File(path).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestWriteFileAllReturnsErrorGivenParentIsAFile(t *testing.T) {
+	t.Parallel()
+	wrote, err := script.Echo("data").WriteFileAll("testdata/hello.txt/out.txt")
+	if err == nil {
+		t.Fatal("want error creating directory over existing file")
+	}
+	if wrote != 0 {
+		t.Errorf("want 0 bytes written, got %d", wrote)
+	}
+}
+
+func TestWriteFileBOMWritesByteOrderMarkBeforeInput(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "out.csv")
+	wrote, err := script.Echo("a,b\n").WriteFileBOM(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrote != 7 {
+		t.Errorf("want 7 bytes written, got %d", wrote)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0xEF, 0xBB, 0xBF, 'a', ',', 'b', '\n'}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestWithContext_AbortsBlockedStageWhenContextIsCancelled(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	r, w := io.Pipe()
+	defer w.Close()
+	_, err := script.NewPipe().WithReader(r).WithContext(ctx).Match("never").String()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestWithContext_DoesNotStartStagesWhenContextIsAlreadyDone(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	got, err := script.NewPipelineContext(ctx).Echo("hello\n").String()
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want context.Canceled, got %v", err)
+	}
+	if got != "" {
+		t.Errorf("want no output, got %q", got)
+	}
+}
+
+func TestWithContext_AppliesToProgramsPassedToRun(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	r, w := io.Pipe()
+	defer w.Close()
+	_, err := script.NewPipelineContext(ctx).WithReader(r).WithStdout(io.Discard).Run(std.Match("never"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestWithContext_AbortsHTTPRequestWhenContextIsCancelled(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := script.NewPipelineContext(ctx).Get(ts.URL).String()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestWithContext_KeepsRequestsOwnDeadline(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+	reqCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = script.NewPipelineContext(context.Background()).Do(req).String()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestWithHeader_SetsHeadersOnRequests(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, r.Header.Get("Authorization"))
+		fmt.Fprintln(w, r.Header.Get("Content-Type"))
+		fmt.Fprintln(w, strings.Join(r.Header.Values("Accept"), ","))
+	}))
+	defer ts.Close()
+	want := "Bearer token\napplication/json\ntext/plain,application/json\n"
+	got, err := script.Echo("{}").
+		WithHeader("Authorization", "Bearer token").
+		WithHeaders(http.Header{"Content-Type": {"application/json"}, "Accept": {"text/plain"}}).
+		WithHeader("Accept", "application/json").
+		Post(ts.URL).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestWithHeader_DoesNotOverrideHeadersSetOnRequest(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, strings.Join(r.Header.Values("Content-Type"), ","))
+	}))
+	defer ts.Close()
+	req, err := http.NewRequest(http.MethodGet, ts.URL, http.NoBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "text/csv")
+	got, err := script.NewPipe().WithHeader("Content-Type", "application/json").Do(req).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "text/csv\n" {
+		t.Errorf("want %q, got %q", "text/csv\n", got)
+	}
+}
+
 func TestWithHTTPClient_SetsSuppliedClientOnPipe(t *testing.T) {
 	t.Parallel()
 	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -1735,6 +4268,60 @@ func TestWithHTTPClient_SetsSuppliedClientOnPipe(t *testing.T) {
 	}
 }
 
+func TestWithProgress_ReportsBytesWrittenPeriodicallyAndAtEnd(t *testing.T) {
+	t.Parallel()
+	input := strings.Repeat("x", 200*1024)
+	var calls []int64
+	buf := new(bytes.Buffer)
+	n, err := script.Echo(input).WithStdout(buf).WithProgress(func(written int64) {
+		calls = append(calls, written)
+	}).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(input)) || buf.Len() != len(input) {
+		t.Fatalf("want %d bytes written, got %d (%d in buffer)", len(input), n, buf.Len())
+	}
+	if len(calls) < 4 {
+		t.Fatalf("want at least 4 progress calls for 200KiB, got %v", calls)
+	}
+	for i := 1; i < len(calls); i++ {
+		if calls[i] < calls[i-1] {
+			t.Errorf("progress went backwards: %v", calls)
+		}
+	}
+	if last := calls[len(calls)-1]; last != n {
+		t.Errorf("want final progress call with total %d, got %d", n, last)
+	}
+}
+
+func TestWithRequestRate_LimitsRateOfHTTPRequests(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+	defer ts.Close()
+	const requests, rate = 5, 20.0
+	p := script.Echo("hello").WithRequestRate(rate)
+	start := time.Now()
+	for i := 0; i < requests; i++ {
+		p.Post(ts.URL)
+	}
+	got, err := p.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "hello" {
+		t.Errorf("want %q, got %q", "hello", got)
+	}
+	// The first request is sent immediately, and each of the others must
+	// wait its turn
+	want := time.Duration(float64(requests-1) / rate * float64(time.Second))
+	if elapsed := time.Since(start); elapsed < want {
+		t.Errorf("want %d requests at %v/s to take at least %v, took %v", requests, rate, want, elapsed)
+	}
+}
+
 func TestWithReader_SetsSuppliedReaderOnPipe(t *testing.T) {
 	t.Parallel()
 	want := "Hello, world."
@@ -1757,6 +4344,90 @@ func TestWithError_SetsSpecifiedErrorOnPipe(t *testing.T) {
 	}
 }
 
+func TestWithTimeout_AbortsHungHTTPRequest(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+	_, err := script.NewPipe().WithTimeout(50 * time.Millisecond).Get(ts.URL).String()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestWithTimeout_AbortsStalledResponseBody(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "partial")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+	got, err := script.NewPipe().WithTimeout(50 * time.Millisecond).Get(ts.URL).String()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want context.DeadlineExceeded, got %v", err)
+	}
+	if got != "partial\n" {
+		t.Errorf("want %q, got %q", "partial\n", got)
+	}
+}
+
+func TestWithTimeout_AllowsRequestsCompletingInTime(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "some data")
+	}))
+	defer ts.Close()
+	got, err := script.NewPipe().WithTimeout(5 * time.Second).Get(ts.URL).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "some data\n" {
+		t.Errorf("want %q, got %q", "some data\n", got)
+	}
+}
+
+func TestWithRetry_ResendsRequestBodyAfterServerErrors(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.Copy(w, r.Body)
+	}))
+	defer ts.Close()
+	got, err := script.Echo("hello").WithRetry(3, time.Millisecond).Post(ts.URL).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "hello" {
+		t.Errorf("want %q, got %q", "hello", got)
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("want 3 attempts, got %d", n)
+	}
+}
+
+func TestWithRetry_SetsLastStatusAsErrorWhenAllAttemptsFail(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+	_, err := script.NewPipe().WithRetry(2, time.Millisecond).Get(ts.URL).String()
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("want error reporting status 502, got %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("want 2 attempts, got %d", n)
+	}
+}
+
 func TestWithStdout_SetsSpecifiedWriterAsStdout(t *testing.T) {
 	t.Parallel()
 	buf := new(bytes.Buffer)
@@ -2287,6 +4958,13 @@ func ExampleSlice() {
 	// 3
 }
 
+// errorWriter is an io.Writer whose writes always fail.
+type errorWriter struct{}
+
+func (errorWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
 // A string containing a line longer than bufio.MaxScanTokenSize, for testing
 // methods that buffer input. We want to make sure they don't throw
 // "bufio.Scanner: token too long" errors.
@@ -3,9 +3,19 @@
 package script_test
 
 import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	script "github.com/bartdeboer/script/v2"
+	xstd "github.com/bartdeboer/script/v2/std"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -98,6 +108,56 @@ func TestExecForEach_CorrectlyEvaluatesTemplateContainingIfStatement(t *testing.
 	}
 }
 
+func TestCommandForEachParallel_StopsStartingCommandsWhenOutputCannotBeWritten(t *testing.T) {
+	t.Parallel()
+	var started int32
+	program := xstd.CommandForEachParallel(func(line string) *exec.Cmd {
+		atomic.AddInt32(&started, 1)
+		return exec.Command("echo", line)
+	}, 2)
+	program.SetStdin(strings.NewReader(strings.Repeat("x\n", 100)))
+	program.SetStdout(errorWriter{})
+	program.SetStderr(io.Discard)
+	if err := program.Start(); err == nil {
+		t.Fatal("want error given failing output")
+	}
+	if n := atomic.LoadInt32(&started); n >= 100 {
+		t.Errorf("want remaining commands not started, got %d started", n)
+	}
+}
+
+func TestExecForEachParallelFunc_OutputsResultsInInputOrder(t *testing.T) {
+	t.Parallel()
+	// Earlier lines take longest, so finish last
+	input := "0.3\n0.2\n0.1\n0\n"
+	start := time.Now()
+	got, err := script.Echo(input).ExecForEachParallelFunc(func(line string) (string, []string) {
+		return "sh", []string{"-c", "sleep $0; echo $0", line}
+	}, 4).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if input != got {
+		t.Error(cmp.Diff(input, got))
+	}
+	if elapsed := time.Since(start); elapsed > 550*time.Millisecond {
+		t.Errorf("commands don't seem to run in parallel, took %v", elapsed)
+	}
+}
+
+func TestExecForEachParallelFunc_ContinuesAfterFailingCommand(t *testing.T) {
+	t.Parallel()
+	got, err := script.Echo("a\nb\nc\n").ExecForEachParallelFunc(func(line string) (string, []string) {
+		return "sh", []string{"-c", `test "$0" != b && echo "$0"`, line}
+	}, 2).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a\nexit status 1\nc\n"; want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
 func TestExecPipesDataToExternalCommandAndGetsExpectedOutput(t *testing.T) {
 	t.Parallel()
 	p := script.File("testdata/hello.txt").Exec("cat")
@@ -111,6 +171,138 @@ func TestExecPipesDataToExternalCommandAndGetsExpectedOutput(t *testing.T) {
 	}
 }
 
+func TestExecTagged_PrefixesLinesWithTheirOriginatingStream(t *testing.T) {
+	t.Parallel()
+	got, err := script.NewPipe().ExecTagged("sh", "-c", "echo out1; echo err1 >&2; echo out2; echo err2 >&2").Slice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr []string
+	for _, line := range got {
+		switch {
+		case strings.HasPrefix(line, "O:"):
+			stdout = append(stdout, strings.TrimPrefix(line, "O:"))
+		case strings.HasPrefix(line, "E:"):
+			stderr = append(stderr, strings.TrimPrefix(line, "E:"))
+		default:
+			t.Errorf("untagged line %q", line)
+		}
+	}
+	if !cmp.Equal([]string{"out1", "out2"}, stdout) {
+		t.Error(cmp.Diff([]string{"out1", "out2"}, stdout))
+	}
+	if !cmp.Equal([]string{"err1", "err2"}, stderr) {
+		t.Error(cmp.Diff([]string{"err1", "err2"}, stderr))
+	}
+}
+
+func TestTagStreams_ReturnsErrorWhenOutputCannotBeWritten(t *testing.T) {
+	t.Parallel()
+	program := xstd.TagStreams(xstd.Command(exec.Command("sh", "-c", "seq 1 10000; echo err >&2")))
+	program.SetStdin(strings.NewReader(""))
+	program.SetStdout(errorWriter{})
+	program.SetStderr(io.Discard)
+	if err := program.Start(); err == nil {
+		t.Fatal("want error given failing output")
+	}
+}
+
+func TestWithContext_KillsRunningCommandWhenContextIsCancelled(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := script.NewPipelineContext(ctx).Exec("sleep", "10").String()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("command was not killed, took %v", elapsed)
+	}
+}
+
+func TestWithDir_RunsSubsequentCommandsInDirectory(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	want, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := script.NewPipe().WithDir(dir).Exec("pwd", "-P").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want+"\n" != got {
+		t.Error(cmp.Diff(want+"\n", got))
+	}
+	got, err = script.Echo("x\n").WithDir(dir).ExecForEach(func(line string) (string, []string) { return "pwd", []string{"-P"} }).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want+"\n" != got {
+		t.Error(cmp.Diff(want+"\n", got))
+	}
+}
+
+func TestWithDir_SetsErrorGivenNonexistentDirectory(t *testing.T) {
+	t.Parallel()
+	_, err := script.NewPipe().WithDir("doesntexist").Exec("pwd").String()
+	if err == nil {
+		t.Error("want error given nonexistent directory")
+	}
+}
+
+func TestWithDir_KeepsPreviousDirectoryGivenNonexistentDirectory(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	want, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := script.NewPipe().WithDir(dir).WithDir("doesntexist")
+	if p.Error() == nil {
+		t.Fatal("want error given nonexistent directory")
+	}
+	p.SetError(nil)
+	got, err := p.Exec("pwd", "-P").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want+"\n" != got {
+		t.Error(cmp.Diff(want+"\n", got))
+	}
+}
+
+func TestWithEnv_SetsEnvironmentForSubsequentCommands(t *testing.T) {
+	t.Parallel()
+	p := script.NewPipe().
+		WithEnv(map[string]string{"SCRIPT_A": "1", "SCRIPT_B": "2"}).
+		WithEnv(map[string]string{"SCRIPT_B": "3"}).
+		WithEnv(map[string]string{})
+	got, err := p.Exec("sh", "-c", `echo "$SCRIPT_A $SCRIPT_B ${HOME:+home}"`).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1 3 home\n"; want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestWithEnv_DoesNotAffectEarlierStages(t *testing.T) {
+	t.Parallel()
+	got, err := script.Echo("x\n").
+		ExecForEach(func(line string) (string, []string) { return "sh", []string{"-c", `echo "[$SCRIPT_C]"`} }).
+		WithEnv(map[string]string{"SCRIPT_C": "set"}).
+		ExecForEach(func(line string) (string, []string) { return "sh", []string{"-c", `echo "$0 [$SCRIPT_C]"`, line} }).
+		String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[] [set]\n"; want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
 func ExampleExec_ok() {
 	script.Exec("echo Hello, world!").Stdout()
 	// Output:
@@ -204,3 +396,97 @@ func ExamplePipe_ExecForEach() {
 	// b
 	// c
 }
+
+func TestWithEnvFile_SetsEnvironmentFromFileForSubsequentCommands(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), ".env")
+	data := "# secrets\nexport SCRIPT_TOKEN=abc123 # comment\nSCRIPT_GREETING=\"hello, world\"\nSCRIPT_LITERAL='a $b #c'\n\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := script.NewPipe().
+		WithEnv(map[string]string{"SCRIPT_TOKEN": "overridden", "SCRIPT_KEPT": "kept"}).
+		WithEnvFile(path).
+		Exec("sh", "-c", `echo "$SCRIPT_TOKEN|$SCRIPT_GREETING|$SCRIPT_LITERAL|$SCRIPT_KEPT"`).
+		String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "abc123|hello, world|a $b #c|kept\n"; want != got {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestWithEnvFile_SetsErrorGivenMalformedFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("SCRIPT_OK=1\nnot a variable\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p := script.NewPipe().WithEnvFile(path)
+	if p.Error() == nil {
+		t.Error("want error given malformed env file")
+	}
+}
+
+func TestWriteFileModeCreatesFileWithPermissions(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "run.sh")
+	if _, err := script.Echo("#!/bin/sh\n").WriteFileMode(path, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := os.FileMode(0o700), info.Mode().Perm(); want != got {
+		t.Errorf("want mode %v, got %v", want, got)
+	}
+}
+
+func TestWriteFileModeChangesPermissionsOfExistingFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := script.Echo("new").WriteFileMode(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := os.FileMode(0o600), info.Mode().Perm(); want != got {
+		t.Errorf("want mode %v, got %v", want, got)
+	}
+}
+
+func TestAppendFileModeAppendsWithPermissions(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "log")
+	if err := os.WriteFile(path, []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wrote, err := script.Echo("b\n").AppendFileMode(path, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrote != 2 {
+		t.Errorf("want 2 bytes written, got %d", wrote)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a\nb\n"; want != string(got) {
+		t.Error(cmp.Diff(want, string(got)))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := os.FileMode(0o600), info.Mode().Perm(); want != got {
+		t.Errorf("want mode %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,5 @@
+package shell
+
+// ExecArgsLimit exposes execArgs to tests, so that chunking can be tested
+// with a small limit.
+var ExecArgsLimit = execArgs
@@ -2,11 +2,13 @@ package shell
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"math"
 	"os/exec"
 	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/bartdeboer/pipeline"
@@ -59,15 +61,233 @@ func Exec(cmdLine string) pipeline.Program {
 	return p
 }
 
+// ExecTagged runs cmdLine as an external command, sending it the contents of
+// the pipe as input, and produces the command's standard output and standard
+// error interleaved, one line at a time. Each line of standard output is
+// prefixed with "O:" and each line of standard error with "E:".
+//
+// The two streams are read concurrently, so lines from each stream appear in
+// their original order, but the relative order of lines from different
+// streams is not guaranteed. Error handling is as for [Exec], except that
+// the standard error text is always tagged and sent to the pipe.
+func ExecTagged(cmdLine string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		args, err := shell.Fields(cmdLine, nil)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("empty command line %q", cmdLine)
+		}
+		stdoutR, stdoutW := io.Pipe()
+		stderrR, stderrW := io.Pipe()
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = p.Stdin
+		cmd.Stdout = stdoutW
+		cmd.Stderr = stderrW
+		if err = cmd.Start(); err != nil {
+			return &pipeline.ExitError{
+				Code:    1,
+				Message: err.Error(),
+			}
+		}
+		mu := new(sync.Mutex)
+		wg := new(sync.WaitGroup)
+		var writeErr error
+		tag := func(r io.Reader, prefix string) {
+			defer wg.Done()
+			scanner := newScanner(r)
+			for scanner.Scan() {
+				mu.Lock()
+				if writeErr == nil {
+					_, writeErr = fmt.Fprintln(p.Stdout, prefix+scanner.Text())
+				}
+				mu.Unlock()
+			}
+			// keep draining, so that the command isn't blocked writing
+			io.Copy(io.Discard, r)
+		}
+		wg.Add(2)
+		go tag(stdoutR, "O:")
+		go tag(stderrR, "E:")
+		err = cmd.Wait()
+		stdoutW.Close()
+		stderrW.Close()
+		wg.Wait()
+		if writeErr != nil {
+			return p.Exit(writeErr)
+		}
+		return err
+	}
+	return p
+}
+
+// argsLimit is the maximum total size in bytes of the arguments ExecArgs
+// passes to a single command. It's well within the limits of common
+// operating systems, which also need room for the environment.
+const argsLimit = 128 * 1024
+
+// ExecArgs runs cmdLine with all the lines of input appended to it as extra
+// arguments, like xargs, and produces the command's output. Each line is
+// passed as a single argument, even if it contains spaces. If the arguments
+// would exceed the operating system's limit on the size of a command line,
+// the lines are split between as many invocations of cmdLine as necessary,
+// which run in sequence. As with [ExecForEach], a command that fails to
+// start or exits with a non-zero status has its error written to the pipe's
+// standard error. If there's no input, the command isn't run.
+//
+// For example:
+//
+//	FindFiles("build").ExecArgs("rm -f").Wait()
+func ExecArgs(cmdLine string) pipeline.Program {
+	return execArgs(cmdLine, argsLimit)
+}
+
+// execArgs is like ExecArgs, but limits the arguments of each command to
+// limit bytes, counting a terminating NUL for each.
+func execArgs(cmdLine string, limit int) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		args, err := shell.Fields(cmdLine, nil)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("empty command line")
+		}
+		size := 0
+		for _, arg := range args {
+			size += len(arg) + 1
+		}
+		run := func(lines []string) {
+			cmd := exec.Command(args[0], append(args[1:len(args):len(args)], lines...)...)
+			cmd.Stdout = p.Stdout
+			cmd.Stderr = p.Stderr
+			if err := cmd.Run(); err != nil {
+				fmt.Fprintln(cmd.Stderr, err)
+			}
+		}
+		var lines []string
+		n := size
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if len(lines) > 0 && n+len(line)+1 > limit {
+				run(lines)
+				lines, n = nil, size
+			}
+			lines = append(lines, line)
+			n += len(line) + 1
+		}
+		if len(lines) > 0 {
+			run(lines)
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// ExecBatch runs cmdLine once for each batch of n lines of input, with the
+// lines appended to it as extra arguments, like xargs -n, and produces the
+// combined output of all these commands in sequence. The final batch may have
+// fewer than n lines. Each line is passed as a single argument, even if it
+// contains spaces. As with [ExecForEach], a command that fails to start or
+// exits with a non-zero status has its error written to the pipe's standard
+// error, and the remaining batches still run. If n is less than 1, the
+// pipe's error status will be set.
+//
+// For example, to remove files 100 at a time:
+//
+//	ListFiles("*.tmp").ExecBatch("rm -f", 100).Wait()
+func ExecBatch(cmdLine string, n int) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		if n < 1 {
+			return fmt.Errorf("invalid batch size %d: must be at least 1", n)
+		}
+		args, err := shell.Fields(cmdLine, nil)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("empty command line")
+		}
+		run := func(batch []string) {
+			cmd := exec.Command(args[0], append(args[1:len(args):len(args)], batch...)...)
+			cmd.Stdout = p.Stdout
+			cmd.Stderr = p.Stderr
+			if err := cmd.Run(); err != nil {
+				fmt.Fprintln(cmd.Stderr, err)
+			}
+		}
+		batch := make([]string, 0, n)
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			batch = append(batch, scanner.Text())
+			if len(batch) == n {
+				run(batch)
+				batch = batch[:0]
+			}
+		}
+		if len(batch) > 0 {
+			run(batch)
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
 // ExecForEach renders cmdLine as a Go template for each line of input, running
 // the resulting command, and produces the combined output of all these
-// commands in sequence. See [Pipe.Exec] for error handling details.
+// commands in sequence. Lines for which cmdLine renders as an empty command
+// are skipped. See [Pipe.Exec] for error handling details.
 //
 // This is mostly useful for substituting data into commands using Go template
 // syntax. For example:
 //
 //	ListFiles("*").ExecForEach("touch {{.}}").Wait()
 func ExecForEach(cmdLine string) pipeline.Program {
+	return execForEach(cmdLine, func(line string) any { return line })
+}
+
+// ExecForEachFields is like [ExecForEach], but splits each line of input into
+// fields, delimited by delim, or by Unicode whitespace if delim is empty, and
+// renders cmdLine with those fields rather than the whole line. If no names
+// are given, the template data is the slice of fields, which can be
+// referenced by index:
+//
+//	Echo("a.txt b.txt").ExecForEachFields("cp {{index . 0}} {{index . 1}}", "")
+//
+// Otherwise, the template data is a map from each of names to the field in
+// the same position, or the empty string if the line has too few fields:
+//
+//	Echo("a.txt b.txt").ExecForEachFields("cp {{.Src}} {{.Dst}}", "", "Src", "Dst")
+func ExecForEachFields(cmdLine string, delim string, names ...string) pipeline.Program {
+	return execForEach(cmdLine, func(line string) any {
+		var fields []string
+		if delim == "" {
+			fields = strings.Fields(line)
+		} else {
+			fields = strings.Split(line, delim)
+		}
+		if len(names) == 0 {
+			return fields
+		}
+		named := make(map[string]string, len(names))
+		for i, name := range names {
+			named[name] = ""
+			if i < len(fields) {
+				named[name] = fields[i]
+			}
+		}
+		return named
+	})
+}
+
+// execForEach renders cmdLine as a Go template with data for each line of
+// input, running the resulting command.
+func execForEach(cmdLine string, data func(line string) any) pipeline.Program {
 	p := pipeline.NewBaseProgram()
 	tpl, err := template.New("").Parse(cmdLine)
 	p.StartFn = func() error {
@@ -77,7 +297,7 @@ func ExecForEach(cmdLine string) pipeline.Program {
 		scanner := newScanner(p.Stdin)
 		for scanner.Scan() {
 			cmdLine := new(strings.Builder)
-			err := tpl.Execute(cmdLine, scanner.Text())
+			err := tpl.Execute(cmdLine, data(scanner.Text()))
 			if err != nil {
 				return err
 			}
@@ -85,6 +305,9 @@ func ExecForEach(cmdLine string) pipeline.Program {
 			if err != nil {
 				return err
 			}
+			if len(args) == 0 {
+				continue
+			}
 			cmd := exec.Command(args[0], args[1:]...)
 			cmd.Stdout = p.Stdout
 			cmd.Stderr = p.Stderr
@@ -103,3 +326,85 @@ func ExecForEach(cmdLine string) pipeline.Program {
 	}
 	return p
 }
+
+// ExecForEachParallel is like [ExecForEach], but runs up to workers commands
+// at once. Each command's output is buffered, so that the combined output is
+// still produced in the order of the input lines, rather than interleaved:
+// first the command's standard output, then its standard error.
+// As with ExecForEach, a command that fails to start or exits with a
+// non-zero status has its error written to the pipe's standard error, and
+// the other commands still run. If a line can't be rendered as a command,
+// no further commands are started, and the pipe's error status is set once
+// the running ones have finished. If the output can't be written, for
+// example because the downstream pipe was closed, no further commands are
+// started either.
+func ExecForEachParallel(cmdLine string, workers int) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	tpl, err := template.New("").Parse(cmdLine)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		if workers < 1 {
+			workers = 1
+		}
+		type output struct {
+			stdout, stderr bytes.Buffer
+		}
+		results := make(chan chan *output, workers)
+		done := make(chan struct{})
+		defer close(done)
+		var renderErr error
+		go func() {
+			defer close(results)
+			sem := make(chan struct{}, workers)
+			scanner := newScanner(p.Stdin)
+			for scanner.Scan() {
+				cmdLine := new(strings.Builder)
+				if renderErr = tpl.Execute(cmdLine, scanner.Text()); renderErr != nil {
+					return
+				}
+				args, err := shell.Fields(cmdLine.String(), nil)
+				if err != nil {
+					renderErr = err
+					return
+				}
+				if len(args) == 0 {
+					continue
+				}
+				result := make(chan *output, 1)
+				select {
+				case results <- result:
+				case <-done:
+					return
+				}
+				select {
+				case sem <- struct{}{}:
+				case <-done:
+					return
+				}
+				go func() {
+					defer func() { <-sem }()
+					out := new(output)
+					cmd := exec.Command(args[0], args[1:]...)
+					cmd.Stdout = &out.stdout
+					cmd.Stderr = &out.stderr
+					if err := cmd.Run(); err != nil {
+						fmt.Fprintln(&out.stderr, err)
+					}
+					result <- out
+				}()
+			}
+			renderErr = scanner.Err()
+		}()
+		for result := range results {
+			out := <-result
+			if _, err := p.Stdout.Write(out.stdout.Bytes()); err != nil {
+				return p.Exit(err)
+			}
+			p.Stderr.Write(out.stderr.Bytes())
+		}
+		return renderErr
+	}
+	return p
+}
@@ -0,0 +1,202 @@
+//go:build !windows
+
+package shell_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bartdeboer/pipeline"
+	"github.com/bartdeboer/script/v2/shell"
+)
+
+func TestExecArgsPassesAllLinesAsArgumentsToSingleCommand(t *testing.T) {
+	t.Parallel()
+	p := pipeline.NewPipeline().WithReader(strings.NewReader("a\nb c\nd\n"))
+	got, err := p.Pipe(shell.ExecArgs("printf [%s]")).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[a][b c][d]"
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestExecArgsSplitsArgumentsBetweenCommandsPastLimit(t *testing.T) {
+	t.Parallel()
+	p := pipeline.NewPipeline().WithReader(strings.NewReader("aaa\nbbb\nccc\nddd\neee\n"))
+	// "echo" and each line take 5 and 4 bytes, so 2 lines fit in 13
+	got, err := p.Pipe(shell.ExecArgsLimit("echo", 13)).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "aaa bbb\nccc ddd\neee\n"
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestExecBatchPassesLinesAsArgumentsInBatchesOfN(t *testing.T) {
+	t.Parallel()
+	p := pipeline.NewPipeline().WithReader(strings.NewReader("a\nb\nc d\ne\nf\n"))
+	got, err := p.Pipe(shell.ExecBatch("echo batch:", 2)).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "batch: a b\nbatch: c d e\nbatch: f\n"
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestExecBatchSetsErrorGivenInvalidBatchSize(t *testing.T) {
+	t.Parallel()
+	p := pipeline.NewPipeline().WithReader(strings.NewReader("a\n"))
+	_, err := p.Pipe(shell.ExecBatch("echo", 0)).String()
+	if err == nil {
+		t.Error("want error given batch size 0")
+	}
+}
+
+func TestExecForEachParallelKeepsOutputInInputOrder(t *testing.T) {
+	t.Parallel()
+	p := pipeline.NewPipeline().WithReader(strings.NewReader("0.3\n0.1\n0.2\n"))
+	got, err := p.Pipe(shell.ExecForEachParallel(`sh -c "sleep {{.}}; echo {{.}}"`, 3)).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "0.3\n0.1\n0.2\n"
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestExecForEachParallelContinuesAfterFailedCommand(t *testing.T) {
+	t.Parallel()
+	stderr := new(strings.Builder)
+	p := pipeline.NewPipeline().WithReader(strings.NewReader("echo a\nfalse\necho b\n")).WithStderr(stderr)
+	got, err := p.Pipe(shell.ExecForEachParallel("{{.}}", 2)).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a\nb\n"
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if !strings.Contains(stderr.String(), "exit status 1") {
+		t.Errorf("want failed command's error on stderr, got %q", stderr.String())
+	}
+}
+
+func TestExecForEachFieldsIndexesWhitespaceDelimitedFields(t *testing.T) {
+	t.Parallel()
+	p := pipeline.NewPipeline().WithReader(strings.NewReader("a  b\nc\td\n"))
+	got, err := p.Pipe(shell.ExecForEachFields("echo {{index . 1}}-{{index . 0}}", "")).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "b-a\nd-c\n"
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestExecForEachFieldsNamesFieldsSplitOnDelim(t *testing.T) {
+	t.Parallel()
+	p := pipeline.NewPipeline().WithReader(strings.NewReader("alice,admin\nbob,dev\n"))
+	got, err := p.Pipe(shell.ExecForEachFields("echo {{.Role}}:{{.User}}", ",", "User", "Role")).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "admin:alice\ndev:bob\n"
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestExecForEachFieldsRendersEmptyStringForMissingNames(t *testing.T) {
+	t.Parallel()
+	p := pipeline.NewPipeline().WithReader(strings.NewReader("x y\n"))
+	got, err := p.Pipe(shell.ExecForEachFields("echo [{{.A}},{{.B}},{{.C}}]", "", "A", "B", "C")).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[x,y,]\n"
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestExecForEachFieldsSkipsLinesRenderingEmptyCommand(t *testing.T) {
+	t.Parallel()
+	p := pipeline.NewPipeline().WithReader(strings.NewReader("echo,a\n,\necho,b\n"))
+	got, err := p.Pipe(shell.ExecForEachFields("{{.Cmd}} {{.Arg}}", ",", "Cmd", "Arg")).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a\nb\n"
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestExecForEachParallelStopsStartingCommandsWhenOutputCannotBeWritten(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	var lines strings.Builder
+	for i := 0; i < 100; i++ {
+		fmt.Fprintln(&lines, i)
+	}
+	program := shell.ExecForEachParallel("touch "+dir+"/{{.}}", 2)
+	program.SetStdin(strings.NewReader(lines.String()))
+	program.SetStdout(errorWriter{})
+	program.SetStderr(io.Discard)
+	if err := program.Start(); err == nil {
+		t.Fatal("want error given failing output")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) >= 100 {
+		t.Errorf("want remaining commands not started, got %d run", len(entries))
+	}
+}
+
+func TestExecTaggedPrefixesLinesWithTheirOriginatingStream(t *testing.T) {
+	t.Parallel()
+	p := pipeline.NewPipeline().WithReader(strings.NewReader(""))
+	got, err := p.Pipe(shell.ExecTagged(`sh -c "echo out1; echo err1 >&2; echo out2"`)).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr []string
+	for _, line := range strings.Split(strings.TrimSuffix(got, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "O:"):
+			stdout = append(stdout, line)
+		case strings.HasPrefix(line, "E:"):
+			stderr = append(stderr, line)
+		default:
+			t.Errorf("want tagged line, got %q", line)
+		}
+	}
+	if want := "O:out1,O:out2"; strings.Join(stdout, ",") != want {
+		t.Errorf("want stdout lines %q, got %q", want, stdout)
+	}
+	if want := "E:err1"; strings.Join(stderr, ",") != want {
+		t.Errorf("want stderr lines %q, got %q", want, stderr)
+	}
+}
+
+// errorWriter is an io.Writer whose writes always fail.
+type errorWriter struct{}
+
+func (errorWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
@@ -0,0 +1,51 @@
+package std
+
+import (
+	"compress/gzip"
+	"io"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// Gunzip decompresses the pipe's contents as gzip data. Input consisting of
+// several concatenated gzip members, as produced by appending to a .gz file,
+// is decompressed as a single stream. If the input isn't valid gzip data,
+// the pipe's error status will be set.
+func Gunzip() pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		zr, err := gzip.NewReader(p.Stdin)
+		if err != nil {
+			return p.Exit(err)
+		}
+		defer zr.Close()
+		// Multistream mode (the default) reads through concatenated members
+		zr.Multistream(true)
+		if _, err := io.Copy(p.Stdout, zr); err != nil {
+			return p.Exit(err)
+		}
+		return nil
+	}
+	return p
+}
+
+// Gzip compresses the pipe's contents as gzip data at the given compression
+// level, such as [gzip.BestCompression] or [gzip.DefaultCompression]. The
+// input is streamed, so it need not fit in memory. If level is invalid, the
+// pipe's error status will be set.
+func Gzip(level int) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	_, err := gzip.NewWriterLevel(io.Discard, level)
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		zw, _ := gzip.NewWriterLevel(p.Stdout, level)
+		if _, err := io.Copy(zw, p.Stdin); err != nil {
+			return p.Exit(err)
+		}
+		return zw.Close()
+	}
+	return p
+}
@@ -0,0 +1,81 @@
+package std
+
+import (
+	"context"
+	"io"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// WithContext returns a program that runs program, but aborts it when ctx is
+// cancelled. On cancellation, the program's input is closed, so that any
+// blocked read returns, and subsequent reads fail with ctx.Err(). The pipe's
+// error status is then set to ctx.Err(). If ctx is already done, program is
+// not started at all.
+//
+// This interrupts any program that reads the pipe, such as a long running
+// filter. A program blocked writing its output is interrupted when the next
+// program, given the same ctx, closes its input. Programs that block
+// elsewhere, such as while running a command or making an HTTP request,
+// should also be given ctx directly.
+func WithContext(ctx context.Context, program pipeline.Program) pipeline.Program {
+	return &contextProgram{Program: program, ctx: ctx}
+}
+
+type contextProgram struct {
+	pipeline.Program
+	ctx   context.Context
+	stdin io.Reader
+}
+
+func (c *contextProgram) SetStdin(stdin io.Reader) {
+	c.stdin = stdin
+	c.Program.SetStdin(contextReader{c.ctx, stdin})
+}
+
+func (c *contextProgram) Start() error {
+	if err := c.ctx.Err(); err != nil {
+		return c.Program.SetError(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			if closer, ok := c.stdin.(io.Closer); ok {
+				closer.Close()
+			}
+		case <-done:
+		}
+	}()
+	err := c.Program.Start()
+	close(done)
+	if ctxErr := c.ctx.Err(); ctxErr != nil {
+		return c.Program.SetError(ctxErr)
+	}
+	return err
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(b []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := c.r.Read(b)
+	if ctxErr := c.ctx.Err(); ctxErr != nil {
+		return n, ctxErr
+	}
+	return n, err
+}
+
+// Close closes the underlying reader, if it is an io.Closer, so that
+// programs exiting early still stop the previous program.
+func (c contextReader) Close() error {
+	if closer, ok := c.r.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
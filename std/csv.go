@@ -0,0 +1,134 @@
+package std
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// CSV reads the input as comma-separated values, as parsed by
+// [encoding/csv], and produces the 1-indexed fields of each record, joined
+// by tabs, one record per line. If no fields are given, all fields are
+// produced. Records may have differing numbers of fields, and fields a
+// record doesn't have are produced as empty strings. If the input is
+// malformed, or any of fields is less than 1, the pipe's error status is
+// set.
+func CSV(fields ...int) pipeline.Program {
+	return CSVDelim(',', fields...)
+}
+
+// CSVDelim is like [CSV], but with fields delimited by delim instead of a
+// comma.
+func CSVDelim(delim rune, fields ...int) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	var err error
+	for _, f := range fields {
+		if f < 1 {
+			err = fmt.Errorf("invalid field %d: fields are numbered from 1", f)
+			break
+		}
+	}
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		r := newCSVReader(p.Stdin, delim)
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return p.Exit(err)
+			}
+			if len(fields) > 0 {
+				selected := make([]string, len(fields))
+				for i, f := range fields {
+					if f <= len(record) {
+						selected[i] = record[f-1]
+					}
+				}
+				record = selected
+			}
+			fmt.Fprintln(p.Stdout, strings.Join(record, "\t"))
+		}
+	}
+	return p
+}
+
+// CSVToJSON reads the input as comma-separated values, as parsed by
+// [encoding/csv], and produces each record as a JSON value, one per line
+// (JSON Lines). If headers is true, the first record supplies the keys, and
+// every other record is produced as an object with those keys, in the same
+// order; fields beyond the headers are keyed "col<n>", where n is the field's
+// 1-indexed position, and missing fields are omitted. Otherwise, each record
+// is produced as an array of strings. Records are produced as they are read.
+// If the input is malformed, the pipe's error status is set.
+func CSVToJSON(headers bool) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		r := newCSVReader(p.Stdin, ',')
+		var keys []string
+		if headers {
+			record, err := r.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return p.Exit(err)
+			}
+			keys = record
+		}
+		buf := new(bytes.Buffer)
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return p.Exit(err)
+			}
+			buf.Reset()
+			if !headers {
+				enc.Encode(record)
+			} else {
+				buf.WriteByte('{')
+				for i, field := range record {
+					key := "col" + strconv.Itoa(i+1)
+					if i < len(keys) {
+						key = keys[i]
+					}
+					if i > 0 {
+						buf.WriteByte(',')
+					}
+					enc.Encode(key)
+					buf.Truncate(buf.Len() - 1) // Encode adds a newline
+					buf.WriteByte(':')
+					enc.Encode(field)
+					buf.Truncate(buf.Len() - 1)
+				}
+				buf.WriteString("}\n")
+			}
+			if _, err := p.Stdout.Write(buf.Bytes()); err != nil {
+				return p.Exit(err)
+			}
+		}
+	}
+	return p
+}
+
+func newCSVReader(r io.Reader, delim rune) *csv.Reader {
+	cr := csv.NewReader(r)
+	cr.Comma = delim
+	cr.FieldsPerRecord = -1
+	return cr
+}
@@ -0,0 +1,242 @@
+package std
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// diffContext is the number of unchanged lines shown around each change in a
+// unified diff.
+const diffContext = 3
+
+// DiffFile compares the pipe's contents with the contents of the file path,
+// line by line, and produces a unified diff, like diff -u path -. If the
+// contents are identical, there is no output. If strict is true, the pipe's
+// error status is also set when the contents differ, which is useful for
+// comparing generated output against a golden file.
+//
+// The diff is minimal as long as there are no more than 1000 inserted and
+// deleted lines. Past that, to bound memory use, everything between the
+// first and last differing lines is shown as one block replacing another.
+func DiffFile(path string, strict bool) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		f, err := os.Open(path)
+		if err != nil {
+			return p.Exit(err)
+		}
+		defer f.Close()
+		want, err := readLines(f)
+		if err != nil {
+			return p.Exit(err)
+		}
+		got, err := readLines(p.Stdin)
+		if err != nil {
+			return p.Exit(err)
+		}
+		edits := diffLines(want, got)
+		if !hasChanges(edits) {
+			return nil
+		}
+		if err := writeUnifiedDiff(p.Stdout, path, "-", edits); err != nil {
+			return p.Exit(err)
+		}
+		if strict {
+			return p.SetError(fmt.Errorf("pipe contents differ from %s", path))
+		}
+		return nil
+	}
+	return p
+}
+
+type diffEdit struct {
+	op   byte // ' ', '-' or '+'
+	line string
+}
+
+func hasChanges(edits []diffEdit) bool {
+	for _, e := range edits {
+		if e.op != ' ' {
+			return true
+		}
+	}
+	return false
+}
+
+// diffMaxEdits is the largest number of inserted and deleted lines diffLines
+// searches for. The search needs memory proportional to the square of the
+// number of edits, so beyond this limit the differing lines are reported as
+// a single replacement instead, which is still a correct, if not minimal,
+// diff.
+const diffMaxEdits = 1000
+
+// diffLines returns an edit script transforming a into b. Lines common to the
+// start and end of both are matched directly, and the rest is compared using
+// Myers' O(ND) difference algorithm, which finds the shortest edit script if
+// it has no more than diffMaxEdits insertions and deletions. Otherwise, the
+// differing lines are all deleted from a and then inserted from b.
+func diffLines(a, b []string) []diffEdit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	var edits []diffEdit
+	for _, line := range a[:prefix] {
+		edits = append(edits, diffEdit{' ', line})
+	}
+	aMid, bMid := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if middle, ok := myersDiff(aMid, bMid, diffMaxEdits); ok {
+		edits = append(edits, middle...)
+	} else {
+		for _, line := range aMid {
+			edits = append(edits, diffEdit{'-', line})
+		}
+		for _, line := range bMid {
+			edits = append(edits, diffEdit{'+', line})
+		}
+	}
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, diffEdit{' ', line})
+	}
+	return edits
+}
+
+// myersDiff returns the shortest edit script transforming a into b, using
+// Myers' O(ND) difference algorithm, and whether one was found with at most
+// maxEdits insertions and deletions.
+func myersDiff(a, b []string, maxEdits int) ([]diffEdit, bool) {
+	n, m := len(a), len(b)
+	max := n + m
+	if max > maxEdits {
+		max = maxEdits
+	}
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace[d] holds the part of v that step d reads, diagonals -d-1 to d+1,
+	// as it was before step d.
+	var trace [][]int
+	found := false
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break search
+			}
+		}
+	}
+	if !found {
+		return nil, false
+	}
+	var edits []diffEdit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v, offset := trace[d], d+1
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, diffEdit{' ', a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, diffEdit{'+', b[prevY]})
+			} else {
+				edits = append(edits, diffEdit{'-', a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits, true
+}
+
+// writeUnifiedDiff writes edits to w in unified diff format, grouping changes
+// into hunks with diffContext lines of surrounding context.
+func writeUnifiedDiff(w io.Writer, fromName, toName string, edits []diffEdit) error {
+	out := new(strings.Builder)
+	fmt.Fprintf(out, "--- %s\n+++ %s\n", fromName, toName)
+	// aLine and bLine hold the 0-based line numbers in a and b at which each
+	// edit begins.
+	aLine := make([]int, len(edits)+1)
+	bLine := make([]int, len(edits)+1)
+	for i, e := range edits {
+		aLine[i+1], bLine[i+1] = aLine[i], bLine[i]
+		if e.op != '+' {
+			aLine[i+1]++
+		}
+		if e.op != '-' {
+			bLine[i+1]++
+		}
+	}
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(edits) && j <= end+2*diffContext; j++ {
+			if edits[j].op != ' ' {
+				end = j
+			}
+		}
+		end += diffContext + 1
+		if end > len(edits) {
+			end = len(edits)
+		}
+		fmt.Fprintf(out, "@@ -%s +%s @@\n",
+			hunkRange(aLine[start], aLine[end]-aLine[start]),
+			hunkRange(bLine[start], bLine[end]-bLine[start]))
+		for _, e := range edits[start:end] {
+			fmt.Fprintf(out, "%c%s\n", e.op, e.line)
+		}
+		i = end
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func hunkRange(start, length int) string {
+	switch length {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
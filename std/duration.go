@@ -0,0 +1,49 @@
+package std
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// HumanizeDuration reads numeric values from column col of each line of
+// input, where the first column is column 1 and columns are delimited by
+// Unicode whitespace, and produces the line with the value, a number of
+// units such as [time.Second] or [time.Nanosecond], rewritten as a
+// human-readable duration like "1h2m3s", as formatted by
+// [time.Duration.String]. The rest of the line is unchanged, and lines whose
+// column col is missing, isn't a number, or is out of the range of a
+// [time.Duration] are produced as they are.
+func HumanizeDuration(col int, unit time.Duration) pipeline.Program {
+	return rewriteColumn(col, func(field string) (string, bool) {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return "", false
+		}
+		d := v * float64(unit)
+		// Reject NaN and values that would overflow a time.Duration,
+		// which converting would silently wrap
+		if !(d >= math.MinInt64 && d < math.MaxInt64) {
+			return "", false
+		}
+		return time.Duration(d).String(), true
+	})
+}
+
+// ParseDuration is the inverse of [HumanizeDuration]. It reads durations
+// like "1h2m3s", in the format accepted by [time.ParseDuration], from column
+// col of each line of input, and produces the line with the duration
+// rewritten as a number of units such as [time.Second]. The rest of the
+// line is unchanged, and lines whose column col is missing or isn't a
+// duration are produced as they are.
+func ParseDuration(col int, unit time.Duration) pipeline.Program {
+	return rewriteColumn(col, func(field string) (string, bool) {
+		d, err := time.ParseDuration(field)
+		if err != nil {
+			return "", false
+		}
+		return formatFloat(float64(d) / float64(unit)), true
+	})
+}
@@ -0,0 +1,121 @@
+package std
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// Base64Decode decodes the pipe's contents from base64 using the standard
+// encoding, as for [Base64Encode]. Newline characters in the input are
+// ignored. If the input isn't valid base64, the pipe's error status will be
+// set.
+func Base64Decode() pipeline.Program {
+	return Base64DecodeWith(base64.StdEncoding)
+}
+
+// Base64DecodeWith is like [Base64Decode], but decodes using the encoding
+// enc, such as [base64.URLEncoding].
+func Base64DecodeWith(enc *base64.Encoding) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		_, err := io.Copy(p.Stdout, base64.NewDecoder(enc, p.Stdin))
+		if err != nil {
+			return p.Exit(err)
+		}
+		return nil
+	}
+	return p
+}
+
+// Base64Encode encodes the pipe's contents as base64 using the standard
+// encoding ([base64.StdEncoding]). The input is streamed, so it need not fit
+// in memory. The output is a single line without a trailing newline.
+func Base64Encode() pipeline.Program {
+	return Base64EncodeWith(base64.StdEncoding)
+}
+
+// Base64EncodeWith is like [Base64Encode], but encodes using the encoding
+// enc, such as [base64.URLEncoding].
+func Base64EncodeWith(enc *base64.Encoding) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		encoder := base64.NewEncoder(enc, p.Stdout)
+		if _, err := io.Copy(encoder, p.Stdin); err != nil {
+			return p.Exit(err)
+		}
+		return encoder.Close()
+	}
+	return p
+}
+
+// DataURI encodes the entire contents of the pipe as a data URI of the form
+// "data:<mimeType>;base64,<payload>", suitable for embedding small files in
+// HTML or CSS. The input is streamed, so it need not fit in memory. The
+// output has no trailing newline.
+func DataURI(mimeType string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		if err := p.Fprint("data:" + mimeType + ";base64,"); err != nil {
+			return err
+		}
+		encoder := base64.NewEncoder(base64.StdEncoding, p.Stdout)
+		if _, err := io.Copy(encoder, p.Stdin); err != nil {
+			return p.Exit(err)
+		}
+		return encoder.Close()
+	}
+	return p
+}
+
+// HexDecode decodes the pipe's contents from hexadecimal, as produced by
+// [HexEncode]. Newline characters in the input are ignored. If the input
+// contains an invalid hex digit, or an odd number of digits, decoding stops
+// there and the pipe's error status is set.
+func HexDecode() pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		_, err := io.Copy(p.Stdout, hex.NewDecoder(newlineSkipper{p.Stdin}))
+		if err != nil {
+			return p.Exit(err)
+		}
+		return nil
+	}
+	return p
+}
+
+// HexEncode encodes the pipe's contents as lowercase hexadecimal, two digits
+// per byte, without a trailing newline. The input is streamed, so it need
+// not fit in memory.
+func HexEncode() pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		if _, err := io.Copy(hex.NewEncoder(p.Stdout), p.Stdin); err != nil {
+			return p.Exit(err)
+		}
+		return nil
+	}
+	return p
+}
+
+// newlineSkipper is a reader that drops '\r' and '\n' bytes from r.
+type newlineSkipper struct {
+	r io.Reader
+}
+
+func (s newlineSkipper) Read(b []byte) (int, error) {
+	for {
+		n, err := s.r.Read(b)
+		kept := b[:0]
+		for _, c := range b[:n] {
+			if c != '\r' && c != '\n' {
+				kept = append(kept, c)
+			}
+		}
+		if len(kept) > 0 || err != nil {
+			return len(kept), err
+		}
+	}
+}
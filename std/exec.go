@@ -0,0 +1,190 @@
+package std
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// Command runs the prepared command cmd, sending it the contents of the pipe
+// as input, and produces the command's standard output. Its standard error
+// goes to the pipe's standard error, which by default is the pipe itself.
+// The command's Stdin, Stdout and Stderr fields are set by Command, but any
+// other configuration, such as its environment, working directory or
+// context, is used as is.
+//
+// If the command can't be started, the pipe's error status is set to a
+// [pipeline.ExitError] with exit status 1. If the command exits with a
+// non-zero status, the pipe's error status is set to the resulting
+// [exec.ExitError], but the command's output is still available in the pipe.
+func Command(cmd *exec.Cmd) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		cmd.Stdin = p.Stdin
+		cmd.Stdout = p.Stdout
+		cmd.Stderr = p.Stderr
+		if err := cmd.Start(); err != nil {
+			return &pipeline.ExitError{
+				Code:    1,
+				Message: err.Error(),
+			}
+		}
+		return cmd.Wait()
+	}
+	return p
+}
+
+// CommandForEach calls build for each line of input, running the resulting
+// command, and produces the combined output of all these commands in
+// sequence. If a command fails to start or exits with a non-zero status, the
+// error is written to the pipe's standard error, and execution continues with
+// the next line.
+func CommandForEach(build func(line string) *exec.Cmd) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			cmd := build(scanner.Text())
+			cmd.Stdout = p.Stdout
+			cmd.Stderr = p.Stderr
+			err := cmd.Start()
+			if err != nil {
+				fmt.Fprintln(cmd.Stderr, err)
+				continue
+			}
+			err = cmd.Wait()
+			if err != nil {
+				fmt.Fprintln(cmd.Stderr, err)
+				continue
+			}
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// CommandForEachParallel is like [CommandForEach], but runs up to workers
+// commands at once. Each command's output is buffered, so that the combined
+// output is still produced in the order of the input lines, rather than
+// interleaved: first the command's standard output, then its standard error.
+// All commands have finished by the time the program exits. If the output
+// can't be written, for example because the downstream pipe was closed, no
+// further commands are started, and the pipe's error status is set.
+func CommandForEachParallel(build func(line string) *exec.Cmd, workers int) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		if workers < 1 {
+			workers = 1
+		}
+		type output struct {
+			stdout, stderr bytes.Buffer
+		}
+		results := make(chan chan *output, workers)
+		done := make(chan struct{})
+		defer close(done)
+		var scanErr error
+		go func() {
+			defer close(results)
+			sem := make(chan struct{}, workers)
+			scanner := newScanner(p.Stdin)
+			for scanner.Scan() {
+				cmd := build(scanner.Text())
+				result := make(chan *output, 1)
+				select {
+				case results <- result:
+				case <-done:
+					return
+				}
+				select {
+				case sem <- struct{}{}:
+				case <-done:
+					return
+				}
+				go func() {
+					defer func() { <-sem }()
+					out := new(output)
+					cmd.Stdout = &out.stdout
+					cmd.Stderr = &out.stderr
+					if err := cmd.Run(); err != nil {
+						fmt.Fprintln(&out.stderr, err)
+					}
+					result <- out
+				}()
+			}
+			scanErr = scanner.Err()
+		}()
+		for result := range results {
+			out := <-result
+			if _, err := p.Stdout.Write(out.stdout.Bytes()); err != nil {
+				return p.Exit(err)
+			}
+			p.Stderr.Write(out.stderr.Bytes())
+		}
+		return scanErr
+	}
+	return p
+}
+
+// CommandTagged runs the prepared command cmd, sending it the contents of
+// the pipe as input, and produces the command's standard output and standard
+// error tagged line by line, as described for [TagStreams]. See [Command] for
+// error handling details.
+func CommandTagged(cmd *exec.Cmd) pipeline.Program {
+	return TagStreams(Command(cmd))
+}
+
+// TagStreams runs program, sending it the contents of the pipe as input, and
+// produces its standard output and standard error interleaved, one line at a
+// time. Each line of standard output is prefixed with "O:" and each line of
+// standard error with "E:", so the two streams can later be separated again.
+//
+// The two streams are read concurrently, so lines from each stream appear in
+// their original order, but the relative order of lines from different
+// streams is not guaranteed. The pipe's error status is set to any error
+// program returns, or if the tagged output can't be written.
+func TagStreams(program pipeline.Program) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.SetError(program.Error())
+	p.StartFn = func() error {
+		if err := program.Error(); err != nil {
+			return err
+		}
+		stdoutR, stdoutW := io.Pipe()
+		stderrR, stderrW := io.Pipe()
+		program.SetStdin(p.Stdin)
+		program.SetStdout(stdoutW)
+		program.SetStderr(stderrW)
+		mu := new(sync.Mutex)
+		wg := new(sync.WaitGroup)
+		var writeErr error
+		tag := func(r io.Reader, prefix string) {
+			defer wg.Done()
+			scanner := newScanner(r)
+			for scanner.Scan() {
+				mu.Lock()
+				if writeErr == nil {
+					_, writeErr = fmt.Fprintln(p.Stdout, prefix+scanner.Text())
+				}
+				mu.Unlock()
+			}
+			// keep draining, so that program isn't blocked writing
+			io.Copy(io.Discard, r)
+		}
+		wg.Add(2)
+		go tag(stdoutR, "O:")
+		go tag(stderrR, "E:")
+		err := program.Start()
+		stdoutW.Close()
+		stderrW.Close()
+		wg.Wait()
+		if writeErr != nil {
+			return p.Exit(writeErr)
+		}
+		return err
+	}
+	return p
+}
@@ -0,0 +1,62 @@
+package std
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// sniffLen is how much of a file RejectBinary examines.
+const sniffLen = 1024
+
+// RejectBinary reads each line of input as a file path, and produces only
+// the paths of files that appear to be text: that is, whose first KB
+// contains no NUL bytes and is valid UTF-8. Paths that can't be read, such
+// as missing files or directories, are skipped.
+func RejectBinary() pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		buf := make([]byte, sniffLen)
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			path := scanner.Text()
+			if isTextFile(path, buf) {
+				fmt.Fprintln(p.Stdout, path)
+			}
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+func isTextFile(path string, buf []byte) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false
+	}
+	b := buf[:n]
+	if bytes.IndexByte(b, 0) >= 0 {
+		return false
+	}
+	if n == len(buf) {
+		// The last rune may have been cut off.
+		for i := 1; i <= utf8.UTFMax && i <= n; i++ {
+			if utf8.RuneStart(b[n-i]) {
+				if !utf8.FullRune(b[n-i:]) {
+					b = b[:n-i]
+				}
+				break
+			}
+		}
+	}
+	return utf8.Valid(b)
+}
@@ -0,0 +1,105 @@
+package std
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// FindFilesDepth is like [std.FindFiles], but doesn't descend more than
+// maxDepth levels below dir, and so doesn't walk the rest of the tree. A
+// depth of 0 produces only the files directly in dir, 1 also those in its
+// subdirectories, and so on.
+func FindFilesDepth(dir string, maxDepth int) pipeline.Program {
+	return findFiles(dir, nil, func(path string, info os.FileInfo) (string, error) {
+		if !info.IsDir() {
+			return path, nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return "", err
+		}
+		if strings.Count(rel, string(filepath.Separator)) >= maxDepth {
+			return "", filepath.SkipDir
+		}
+		return "", nil
+	})
+}
+
+// FindFilesInfo is like [std.FindFiles], but produces a tab-separated line
+// for each file with its path, size in bytes, mode, and modification time in
+// RFC 3339 format, for example:
+//
+//	test/1.txt	12	-rw-r--r--	2024-01-02T15:04:05Z
+func FindFilesInfo(dir string) pipeline.Program {
+	return findFiles(dir, nil, func(path string, info os.FileInfo) (string, error) {
+		if info.IsDir() {
+			return "", nil
+		}
+		return fmt.Sprintf("%s\t%d\t%s\t%s", path, info.Size(), info.Mode(), info.ModTime().Format(time.RFC3339)), nil
+	})
+}
+
+// FindFilesMatch is like [std.FindFiles], but produces only the files whose
+// base name matches the shell pattern, as interpreted by [filepath.Match].
+// For example, the pattern "*.go" matches Go source files at any depth. If
+// pattern is malformed, the pipe's error status will be set.
+func FindFilesMatch(dir, pattern string) pipeline.Program {
+	_, err := filepath.Match(pattern, "")
+	return findFiles(dir, err, func(path string, info os.FileInfo) (string, error) {
+		if ok, _ := filepath.Match(pattern, info.Name()); ok && !info.IsDir() {
+			return path, nil
+		}
+		return "", nil
+	})
+}
+
+// FindFilesRegexp is like [std.FindFiles], but produces only the files whose
+// path, starting with the initial directory, matches re.
+func FindFilesRegexp(dir string, re *regexp.Regexp) pipeline.Program {
+	return findFiles(dir, nil, func(path string, info os.FileInfo) (string, error) {
+		if !info.IsDir() && re.MatchString(path) {
+			return path, nil
+		}
+		return "", nil
+	})
+}
+
+// findFiles walks dir like [std.FindFiles], calling visit for each file and
+// directory, including dir itself. visit returns the line to produce for the
+// entry, if any, or [filepath.SkipDir] to skip a directory. If err is not
+// nil, it is set as the pipe's error status instead.
+func findFiles(dir string, err error, visit func(path string, info os.FileInfo) (string, error)) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	if err == nil {
+		_, err = os.Stat(dir)
+	}
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return p.Exit(err)
+			}
+			line, err := visit(path, info)
+			if err != nil {
+				return err
+			}
+			if line != "" {
+				if err := p.Fprint(line + "\n"); err != nil {
+					return p.Exit(err)
+				}
+			}
+			return nil
+		})
+		return p.SetError(err)
+	}
+	return p
+}
@@ -0,0 +1,78 @@
+package std
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// followInterval is how often FollowFile checks for new data.
+var followInterval = 100 * time.Millisecond
+
+// FollowFile produces the contents of the file path, and then continues to
+// produce any data appended to it, like tail -f, until ctx is cancelled, at
+// which point the pipe's error status is set to ctx.Err(). It also stops if
+// the pipe's output is closed. Because its output is unbounded, callers
+// should always arrange for ctx to be cancelled eventually.
+//
+// The file is polled for changes. If it is truncated, FollowFile starts
+// reading again from the beginning; if it is replaced by a different file
+// (as in log rotation), FollowFile finishes reading the old file and then
+// reopens path.
+func FollowFile(ctx context.Context, path string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	_, err := os.Stat(path)
+	p.SetError(err)
+	p.StartFn = func() error {
+		f, err := os.Open(path)
+		if err != nil {
+			return p.Exit(err)
+		}
+		defer func() { f.Close() }()
+		ticker := time.NewTicker(followInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := io.Copy(p.Stdout, f); err != nil {
+				return p.Exit(err)
+			}
+			select {
+			case <-ctx.Done():
+				return p.Exit(ctx.Err())
+			case <-ticker.C:
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				continue // the file may be missing briefly during rotation
+			}
+			current, err := f.Stat()
+			if err != nil {
+				return p.Exit(err)
+			}
+			if !os.SameFile(info, current) {
+				if _, err := io.Copy(p.Stdout, f); err != nil {
+					return p.Exit(err)
+				}
+				next, err := os.Open(path)
+				if err != nil {
+					continue
+				}
+				f.Close()
+				f = next
+				continue
+			}
+			offset, err := f.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return p.Exit(err)
+			}
+			if info.Size() < offset {
+				if _, err := f.Seek(0, io.SeekStart); err != nil {
+					return p.Exit(err)
+				}
+			}
+		}
+	}
+	return p
+}
@@ -0,0 +1,124 @@
+package std
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// ListFilesGlob is like [std.ListFiles], but also supports the recursive
+// wildcard ** as a whole path element, which matches zero or more
+// directories. For example, "src/**/*.go" matches src/main.go as well as
+// src/cmd/tool/main.go. Other path elements are matched as for
+// [filepath.Match]. The matching files and directories are produced one per
+// line in sorted order. As for [filepath.Glob], I/O errors while walking the
+// tree are ignored, and a malformed pattern sets the pipe's error status.
+//
+// If pattern contains no wildcards, ListFilesGlob produces the contents of
+// the directory, or the path itself if it's a file, without walking.
+func ListFilesGlob(pattern string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	listing, err := globFiles(pattern)
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		return writeLines(p, listing)
+	}
+	return p
+}
+
+func globFiles(pattern string) ([]string, error) {
+	elems := strings.Split(filepath.ToSlash(pattern), "/")
+	i := 0
+	for i < len(elems) && !hasGlobMeta(elems[i]) {
+		i++
+	}
+	if i == len(elems) {
+		return listDir(pattern)
+	}
+	for _, elem := range elems[i:] {
+		if _, err := path.Match(elem, ""); err != nil {
+			return nil, err
+		}
+	}
+	root := filepath.FromSlash(strings.Join(elems[:i], "/"))
+	if i == 0 {
+		root = "."
+	} else if root == "" {
+		root = string(filepath.Separator)
+	}
+	rest := elems[i:]
+	recursive := false
+	for _, elem := range rest {
+		recursive = recursive || elem == "**"
+	}
+	var matches []string
+	filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || name == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return nil
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if matchGlobElems(rest, parts) {
+			matches = append(matches, name)
+		}
+		if d.IsDir() && !recursive && len(parts) >= len(rest) {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// listDir returns the entries of the directory dir, or dir itself if it's a
+// file.
+func listDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		info, statErr := os.Stat(dir)
+		if statErr != nil {
+			return nil, statErr
+		}
+		if !info.IsDir() {
+			return []string{dir}, nil
+		}
+		return nil, err
+	}
+	listing := make([]string, len(entries))
+	for i, e := range entries {
+		listing[i] = filepath.Join(dir, e.Name())
+	}
+	return listing, nil
+}
+
+// matchGlobElems reports whether the path elements parts match the pattern
+// elements pattern, where ** matches zero or more elements.
+func matchGlobElems(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		return matchGlobElems(pattern[1:], parts) ||
+			len(parts) > 0 && matchGlobElems(pattern, parts[1:])
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], parts[0])
+	return ok && matchGlobElems(pattern[1:], parts[1:])
+}
+
+func hasGlobMeta(elem string) bool {
+	return strings.ContainsAny(elem, `*?[\`)
+}
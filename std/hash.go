@@ -0,0 +1,90 @@
+package std
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// HashSum produces the hex-encoded hash of the entire contents of the pipe,
+// computed using a hash created by newHash.
+func HashSum(newHash func() hash.Hash) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		sum, err := hashReader(newHash, p.Stdin)
+		if err != nil {
+			return p.Exit(err)
+		}
+		return p.Fprint(sum)
+	}
+	return p
+}
+
+// HashSums reads paths from the pipe, one per line, and produces the
+// hex-encoded hash of each corresponding file, computed using a hash created
+// by newHash, one per line. Any files that cannot be opened or read will be
+// ignored.
+func HashSums(newHash func() hash.Hash) pipeline.Program {
+	return pipeline.Scanner(func(line string, w io.Writer) {
+		f, err := os.Open(line)
+		if err != nil {
+			return // skip unopenable files
+		}
+		defer f.Close()
+		sum, err := hashReader(newHash, f)
+		if err != nil {
+			return // skip unreadable files
+		}
+		fmt.Fprintln(w, sum)
+	})
+}
+
+// MD5Sum produces the hex-encoded MD5 hash of the entire contents of the pipe.
+func MD5Sum() pipeline.Program {
+	return HashSum(md5.New)
+}
+
+// MD5Sums produces the hex-encoded MD5 hash of each file whose path is read
+// from the pipe, as for [HashSums].
+func MD5Sums() pipeline.Program {
+	return HashSums(md5.New)
+}
+
+// SHA1Sum produces the hex-encoded SHA-1 hash of the entire contents of the
+// pipe.
+func SHA1Sum() pipeline.Program {
+	return HashSum(sha1.New)
+}
+
+// SHA1Sums produces the hex-encoded SHA-1 hash of each file whose path is
+// read from the pipe, as for [HashSums].
+func SHA1Sums() pipeline.Program {
+	return HashSums(sha1.New)
+}
+
+// SHA512Sum produces the hex-encoded SHA-512 hash of the entire contents of
+// the pipe.
+func SHA512Sum() pipeline.Program {
+	return HashSum(sha512.New)
+}
+
+// SHA512Sums produces the hex-encoded SHA-512 hash of each file whose path
+// is read from the pipe, as for [HashSums].
+func SHA512Sums() pipeline.Program {
+	return HashSums(sha512.New)
+}
+
+func hashReader(newHash func() hash.Hash, r io.Reader) (string, error) {
+	hasher := newHash()
+	if _, err := io.Copy(hasher, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
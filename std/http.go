@@ -0,0 +1,444 @@
+package std
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// Doer sends HTTP requests. It is implemented by [*http.Client], and by the
+// wrappers in this package that add behaviour such as rate limiting.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Do performs the HTTP request req using c, and produces the response body.
+// If the response status is anything other than HTTP 200-299, the pipe's
+// error status is set.
+func Do(req *http.Request, c Doer) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		return do(p, req, c)
+	}
+	return p
+}
+
+// Get makes an HTTP GET request to url using c, sending the contents of the
+// pipe as the request body, and produces the server's response. See [Do] for
+// how the HTTP response status is interpreted.
+func Get(url string, c Doer) pipeline.Program {
+	return method(http.MethodGet, url, c)
+}
+
+// Post makes an HTTP POST request to url using c, sending the contents of the
+// pipe as the request body, and produces the server's response. See [Do] for
+// how the HTTP response status is interpreted.
+func Post(url string, c Doer) pipeline.Program {
+	return method(http.MethodPost, url, c)
+}
+
+// Put makes an HTTP PUT request to url using c, sending the contents of the
+// pipe as the request body, and produces the server's response. See [Do] for
+// how the HTTP response status is interpreted.
+func Put(url string, c Doer) pipeline.Program {
+	return method(http.MethodPut, url, c)
+}
+
+// Patch makes an HTTP PATCH request to url using c, sending the contents of
+// the pipe as the request body, and produces the server's response. See [Do]
+// for how the HTTP response status is interpreted.
+func Patch(url string, c Doer) pipeline.Program {
+	return method(http.MethodPatch, url, c)
+}
+
+// Delete makes an HTTP DELETE request to url using c, and produces the
+// server's response. The contents of the pipe are sent as the request body,
+// but unlike the other methods, they are read in full first, so that an
+// empty pipe results in a request with no body at all. See [Do] for how the
+// HTTP response status is interpreted.
+func Delete(url string, c Doer) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		body, err := io.ReadAll(p.Stdin)
+		if err != nil {
+			return p.Exit(err)
+		}
+		req, err := http.NewRequest(http.MethodDelete, url, bytes.NewReader(body))
+		if err != nil {
+			return p.Exit(err)
+		}
+		return do(p, req, c)
+	}
+	return p
+}
+
+// PostJSON makes an HTTP POST request to url using c, sending v encoded as
+// JSON as the request body, with a Content-Type of application/json, and
+// produces the server's response. The contents of the pipe are ignored. If
+// v can't be encoded, the pipe's error status is set, and no request is
+// made. See [Do] for how the HTTP response status is interpreted.
+func PostJSON(url string, v any, c Doer) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	data, err := json.Marshal(v)
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+		if err != nil {
+			return p.Exit(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return do(p, req, c)
+	}
+	return p
+}
+
+func method(method, url string, c Doer) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		req, err := http.NewRequest(method, url, p.Stdin)
+		if err != nil {
+			return p.Exit(err)
+		}
+		return do(p, req, c)
+	}
+	return p
+}
+
+func do(p *pipeline.BaseProgram, req *http.Request, c Doer) error {
+	resp, err := c.Do(req)
+	if err != nil {
+		return p.Exit(err)
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(p.Stdout, resp.Body)
+	if err != nil {
+		return p.Exit(err)
+	}
+	if err := checkStatus(resp); err != nil {
+		return p.Exit(err)
+	}
+	return nil
+}
+
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected HTTP response status: %s", resp.Status)
+	}
+	return nil
+}
+
+// GetAll makes an HTTP GET request to each of urls using c, with at most
+// concurrency requests in flight at once, and produces the response bodies
+// in the order of urls, regardless of the order in which they complete. If
+// any request fails, the pipe's error status is set to the error of the
+// first one to fail, in the order of urls, but the other responses are
+// still produced. If the output can't be written, no further requests are
+// made. See [Do] for how the HTTP response status is interpreted.
+func GetAll(urls []string, concurrency int, c Doer) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		if concurrency < 1 {
+			concurrency = 1
+		}
+		type result struct {
+			body []byte
+			err  error
+		}
+		results := make([]chan result, len(urls))
+		for i := range results {
+			results[i] = make(chan result, 1)
+		}
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			sem := make(chan struct{}, concurrency)
+			for i, url := range urls {
+				select {
+				case sem <- struct{}{}:
+				case <-done:
+					return
+				}
+				go func(i int, url string) {
+					defer func() { <-sem }()
+					body, err := fetch(url, c)
+					results[i] <- result{body, err}
+				}(i, url)
+			}
+		}()
+		var firstErr error
+		for _, ch := range results {
+			r := <-ch
+			if r.err != nil && firstErr == nil {
+				firstErr = r.err
+			}
+			if _, err := p.Stdout.Write(r.body); err != nil {
+				return p.Exit(err)
+			}
+		}
+		if firstErr != nil {
+			return p.Exit(firstErr)
+		}
+		return nil
+	}
+	return p
+}
+
+func fetch(url string, c Doer) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return body, err
+	}
+	return body, checkStatus(resp)
+}
+
+// Header returns a Doer that sends requests using c, adding the headers in
+// h to each one. Headers already set on a request take precedence over
+// those in h with the same key.
+func Header(c Doer, h http.Header) Doer {
+	return headerDoer{c, h}
+}
+
+type headerDoer struct {
+	c Doer
+	h http.Header
+}
+
+func (d headerDoer) Do(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for key, values := range d.h {
+		if _, ok := req.Header[key]; ok {
+			continue
+		}
+		req.Header[key] = append([]string(nil), values...)
+	}
+	return d.c.Do(req)
+}
+
+// RequestContext returns a Doer that sends requests using c, aborting them
+// when ctx is cancelled, including while reading the response body. Each
+// request's own context still applies, so a request is aborted when either
+// context is done. When a request is aborted because of ctx, the error
+// matches ctx.Err() using [errors.Is].
+func RequestContext(c Doer, ctx context.Context) Doer {
+	return contextDoer{c, ctx}
+}
+
+type contextDoer struct {
+	c   Doer
+	ctx context.Context
+}
+
+func (d contextDoer) Do(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	go func() {
+		select {
+		case <-d.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	resp, err := d.c.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, d.wrap(err)
+	}
+	resp.Body = &contextBody{resp.Body, cancel, d}
+	return resp, nil
+}
+
+// wrap reports err as caused by d.ctx if that has been cancelled.
+func (d contextDoer) wrap(err error) error {
+	if ctxErr := d.ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		return fmt.Errorf("HTTP request aborted: %w", ctxErr)
+	}
+	return err
+}
+
+// contextBody releases the request's context when the body is closed.
+type contextBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+	d      contextDoer
+}
+
+func (b *contextBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		err = b.d.wrap(err)
+	}
+	return n, err
+}
+
+func (b *contextBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// RateLimiter spaces out events so that they occur no more often than a
+// given rate. It is safe for concurrent use.
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing perSecond events per second.
+// If perSecond isn't positive, events are not limited.
+func NewRateLimiter(perSecond float64) *RateLimiter {
+	l := &RateLimiter{}
+	if perSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / perSecond)
+	}
+	return l
+}
+
+// Wait blocks until the next event is allowed to occur.
+func (l *RateLimiter) Wait() {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+	time.Sleep(delay)
+}
+
+// RateLimit returns a Doer that sends requests using c, waiting for l before
+// each one. Sharing l between several Doers limits their combined rate.
+func RateLimit(c Doer, l *RateLimiter) Doer {
+	return rateLimitedDoer{c, l}
+}
+
+type rateLimitedDoer struct {
+	c Doer
+	l *RateLimiter
+}
+
+func (d rateLimitedDoer) Do(req *http.Request) (*http.Response, error) {
+	d.l.Wait()
+	return d.c.Do(req)
+}
+
+// Retry returns a Doer that sends requests using c, re-sending each one up
+// to attempts times in total if it fails with an error or a 5xx status code.
+// It waits for backoff before the first retry, doubling the wait each time.
+// The request body is buffered so that it can be sent again. If every
+// attempt fails, the last error or response is returned.
+func Retry(c Doer, attempts int, backoff time.Duration) Doer {
+	return retryDoer{c, attempts, backoff}
+}
+
+type retryDoer struct {
+	c        Doer
+	attempts int
+	backoff  time.Duration
+}
+
+func (d retryDoer) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	wait := d.backoff
+	for attempt := 1; ; attempt++ {
+		r := req.Clone(req.Context())
+		if body != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+		}
+		resp, err := d.c.Do(r)
+		if attempt >= d.attempts || (err == nil && resp.StatusCode < 500) {
+			return resp, err
+		}
+		if err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		wait *= 2
+	}
+}
+
+// Timeout returns a Doer that sends requests using c, aborting each one if
+// it has not completed within d, including reading the response body. When
+// a request times out, the error matches [context.DeadlineExceeded] using
+// [errors.Is].
+func Timeout(c Doer, d time.Duration) Doer {
+	return timeoutDoer{c, d}
+}
+
+type timeoutDoer struct {
+	c Doer
+	d time.Duration
+}
+
+func (d timeoutDoer) Do(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), d.d)
+	resp, err := d.c.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, d.wrap(ctx, err)
+	}
+	resp.Body = &timeoutBody{resp.Body, ctx, cancel, d}
+	return resp, nil
+}
+
+// wrap reports err as a timeout if it was caused by the deadline on ctx.
+func (d timeoutDoer) wrap(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("HTTP request timed out after %v: %w", d.d, context.DeadlineExceeded)
+	}
+	return err
+}
+
+// timeoutBody releases the request's context when the body is closed.
+type timeoutBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	cancel context.CancelFunc
+	d      timeoutDoer
+}
+
+func (b *timeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		err = b.d.wrap(b.ctx, err)
+	}
+	return n, err
+}
+
+func (b *timeoutBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,283 @@
+package std
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// CanonicalJSON reads the pipe's contents as a stream of JSON values and
+// produces each value in a canonical form: object keys are sorted
+// recursively, insignificant whitespace is removed, and each value is
+// followed by a newline. Numbers are reproduced exactly as they appear in the
+// input. Two documents that differ only in formatting or key order therefore
+// produce byte-identical output, suitable for hashing or diffing.
+func CanonicalJSON() pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		dec := json.NewDecoder(p.Stdin)
+		dec.UseNumber()
+		enc := json.NewEncoder(p.Stdout)
+		enc.SetEscapeHTML(false)
+		for {
+			var v interface{}
+			err := dec.Decode(&v)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return p.Exit(err)
+			}
+			// encoding/json marshals map keys in sorted order
+			if err := enc.Encode(v); err != nil {
+				return p.Exit(err)
+			}
+		}
+	}
+	return p
+}
+
+// JSONNumbersToStrings reads the pipe's contents as a stream of JSON values
+// and produces each value with every number, at any depth, replaced by a
+// string containing the number exactly as it appears in the input. This
+// preserves the precision of values such as large 64-bit IDs when passing
+// them through tools that parse JSON numbers as float64. Like
+// [CanonicalJSON], object keys are sorted and each value is followed by a
+// newline.
+func JSONNumbersToStrings() pipeline.Program {
+	return transformJSON(func(v interface{}) interface{} {
+		if n, ok := v.(json.Number); ok {
+			return n.String()
+		}
+		return v
+	})
+}
+
+// JSONStringsToNumbers is the inverse of [JSONNumbersToStrings]: it produces
+// each JSON value with every string that is a valid JSON number, such as
+// "9007199254740993" or "-1.5e3", replaced by that number, reproduced
+// exactly. Other strings are unchanged.
+func JSONStringsToNumbers() pipeline.Program {
+	return transformJSON(func(v interface{}) interface{} {
+		if s, ok := v.(string); ok && jsonNumber.MatchString(s) {
+			return json.Number(s)
+		}
+		return v
+	})
+}
+
+// jsonNumber matches the JSON number grammar.
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// JSONPrettyStreaming reads the pipe's contents as a stream of JSON values and
+// produces each one pretty-printed, with nested elements indented by indent,
+// as for [json.Indent]. As the whole input is buffered in memory before any
+// output is produced, it refuses to read more than maxBuffer bytes: if the
+// input is larger, there is no output, and the pipe's error status is set
+// instead. It's also set if the input isn't valid JSON, or if maxBuffer is
+// negative. Use [math.MaxInt64] for no limit.
+func JSONPrettyStreaming(indent string, maxBuffer int64) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	var err error
+	if maxBuffer < 0 {
+		err = fmt.Errorf("invalid buffer limit %d: must not be negative", maxBuffer)
+	}
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		limit := maxBuffer
+		if limit < math.MaxInt64 {
+			limit++
+		}
+		data, err := io.ReadAll(io.LimitReader(p.Stdin, limit))
+		if err != nil {
+			return p.Exit(err)
+		}
+		if int64(len(data)) > maxBuffer {
+			return p.Exit(fmt.Errorf("JSON input exceeds limit of %d bytes", maxBuffer))
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		out := new(bytes.Buffer)
+		for {
+			var v json.RawMessage
+			err := dec.Decode(&v)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return p.Exit(err)
+			}
+			if err := json.Indent(out, v, "", indent); err != nil {
+				return p.Exit(err)
+			}
+			out.WriteByte('\n')
+		}
+		if _, err := p.Stdout.Write(out.Bytes()); err != nil {
+			return p.Exit(err)
+		}
+		return nil
+	}
+	return p
+}
+
+// transformJSON returns a program like [CanonicalJSON] that replaces each
+// scalar value v, at any depth, with fn(v). Numbers are passed to fn as
+// [json.Number].
+func transformJSON(fn func(v interface{}) interface{}) pipeline.Program {
+	var transform func(v interface{}) interface{}
+	transform = func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, elem := range v {
+				v[k] = transform(elem)
+			}
+			return v
+		case []interface{}:
+			for i, elem := range v {
+				v[i] = transform(elem)
+			}
+			return v
+		}
+		return fn(v)
+	}
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		dec := json.NewDecoder(p.Stdin)
+		dec.UseNumber()
+		enc := json.NewEncoder(p.Stdout)
+		enc.SetEscapeHTML(false)
+		for {
+			var v interface{}
+			err := dec.Decode(&v)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return p.Exit(err)
+			}
+			if err := enc.Encode(transform(v)); err != nil {
+				return p.Exit(err)
+			}
+		}
+	}
+	return p
+}
+
+// JSONKeys reads the pipe's contents as a JSON object and produces its
+// top-level keys in sorted order, one per line. If the input isn't a JSON
+// object, the pipe's error status will be set.
+func JSONKeys() pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		var obj map[string]json.RawMessage
+		if err := json.NewDecoder(p.Stdin).Decode(&obj); err != nil {
+			return p.Exit(err)
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return writeLines(p, keys)
+	}
+	return p
+}
+
+// JSONKeysDeep reads the pipe's contents as a JSON value and produces the
+// dotted path to every leaf value, in sorted order, one per line. Array
+// elements are identified by their index, so for example the input
+// {"a":{"b":1},"c":[true]} produces the paths "a.b" and "c.0". Empty objects
+// and arrays are treated as leaves.
+func JSONKeysDeep() pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		var v interface{}
+		if err := json.NewDecoder(p.Stdin).Decode(&v); err != nil {
+			return p.Exit(err)
+		}
+		var paths []string
+		var walk func(prefix string, v interface{})
+		join := func(prefix, key string) string {
+			if prefix == "" {
+				return key
+			}
+			return prefix + "." + key
+		}
+		walk = func(prefix string, v interface{}) {
+			switch v := v.(type) {
+			case map[string]interface{}:
+				if len(v) == 0 && prefix != "" {
+					paths = append(paths, prefix)
+				}
+				for k, elem := range v {
+					walk(join(prefix, k), elem)
+				}
+			case []interface{}:
+				if len(v) == 0 && prefix != "" {
+					paths = append(paths, prefix)
+				}
+				for i, elem := range v {
+					walk(join(prefix, strconv.Itoa(i)), elem)
+				}
+			default:
+				if prefix != "" {
+					paths = append(paths, prefix)
+				}
+			}
+		}
+		walk("", v)
+		sort.Strings(paths)
+		return writeLines(p, paths)
+	}
+	return p
+}
+
+// LinesToJSON reads all the lines of the pipe's contents and produces them
+// as a single JSON array of strings, such as ["a","b"], followed by a
+// newline. Quotes, backslashes and control characters in the lines are
+// escaped as JSON requires. If there are no lines, the output is an empty
+// array. See [JSONToLines] for the inverse.
+func LinesToJSON() pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		lines, err := readLines(p.Stdin)
+		if err != nil {
+			return p.Exit(err)
+		}
+		if lines == nil {
+			lines = []string{}
+		}
+		enc := json.NewEncoder(p.Stdout)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(lines); err != nil {
+			return p.Exit(err)
+		}
+		return nil
+	}
+	return p
+}
+
+// JSONToLines reads the pipe's contents as a JSON array of strings and
+// produces each element unescaped, followed by a newline. If the input isn't
+// a JSON array of strings, the pipe's error status will be set. See
+// [LinesToJSON] for the inverse.
+func JSONToLines() pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		var lines []string
+		if err := json.NewDecoder(p.Stdin).Decode(&lines); err != nil {
+			return p.Exit(err)
+		}
+		return writeLines(p, lines)
+	}
+	return p
+}
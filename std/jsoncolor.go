@@ -0,0 +1,141 @@
+package std
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// ANSI escape sequences used by JSONColor, similar to jq's default colors.
+const (
+	colorReset  = "\x1b[0m"
+	colorKey    = "\x1b[34;1m"
+	colorString = "\x1b[32m"
+	colorNumber = "\x1b[36m"
+	colorBool   = "\x1b[33m"
+	colorNull   = "\x1b[90m"
+)
+
+// JSONColor reads the pipe's contents as a stream of JSON values and produces
+// each one pretty-printed with an indent of two spaces, like jq. If the
+// process's standard output is a terminal, object keys, strings, numbers,
+// booleans and null are also highlighted with ANSI colors. Object keys are
+// produced in their original order, and numbers exactly as they appear in
+// the input. If the input isn't valid JSON, the pipe's error status will be
+// set.
+func JSONColor() pipeline.Program {
+	return JSONColorWith(isTerminal(os.Stdout))
+}
+
+// JSONColorWith is like [JSONColor], but highlights the output with ANSI
+// colors if color is true, whether or not standard output is a terminal.
+func JSONColorWith(color bool) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		dec := json.NewDecoder(p.Stdin)
+		dec.UseNumber()
+		w := bufio.NewWriter(p.Stdout)
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				return nil
+			}
+			if err == nil {
+				err = writeColorJSON(w, dec, tok, "", color)
+			}
+			if err == nil {
+				w.WriteByte('\n')
+				err = w.Flush()
+			}
+			if err != nil {
+				return p.Exit(err)
+			}
+		}
+	}
+	return p
+}
+
+// writeColorJSON writes the JSON value beginning with tok to w, reading the
+// rest of it from dec, indented by indent.
+func writeColorJSON(w *bufio.Writer, dec *json.Decoder, tok json.Token, indent string, color bool) error {
+	paint := func(code, s string) {
+		if color {
+			w.WriteString(code + s + colorReset)
+			return
+		}
+		w.WriteString(s)
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		open, closing := "[", "]"
+		if t == '{' {
+			open, closing = "{", "}"
+		}
+		inner := indent + "  "
+		n := 0
+		for ; dec.More(); n++ {
+			if n == 0 {
+				w.WriteString(open + "\n" + inner)
+			} else {
+				w.WriteString(",\n" + inner)
+			}
+			if t == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				paint(colorKey, quoteJSON(key.(string)))
+				w.WriteString(": ")
+			}
+			value, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if err := writeColorJSON(w, dec, value, inner, color); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		if n == 0 {
+			w.WriteString(open + closing)
+		} else {
+			w.WriteString("\n" + indent + closing)
+		}
+	case string:
+		paint(colorString, quoteJSON(t))
+	case json.Number:
+		paint(colorNumber, t.String())
+	case bool:
+		if t {
+			paint(colorBool, "true")
+		} else {
+			paint(colorBool, "false")
+		}
+	case nil:
+		paint(colorNull, "null")
+	}
+	return nil
+}
+
+// quoteJSON returns s encoded as a JSON string, without escaping HTML
+// characters.
+func quoteJSON(s string) string {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// isTerminal reports whether f is a terminal, or at least a character
+// device.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
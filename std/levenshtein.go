@@ -0,0 +1,67 @@
+package std
+
+import (
+	"fmt"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// NearestLine produces the line of the pipe's contents with the smallest
+// Levenshtein (edit) distance to target, counted in runes, such as for
+// "did you mean" suggestions. If several lines are equally near, the first
+// of them is produced. If there are no lines, there is no output.
+func NearestLine(target string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		want := []rune(target)
+		var nearest string
+		best := -1
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if d := levenshtein([]rune(line), want); best < 0 || d < best {
+				nearest, best = line, d
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return p.Exit(err)
+		}
+		if best < 0 {
+			return nil
+		}
+		_, err := fmt.Fprintln(p.Stdout, nearest)
+		return err
+	}
+	return p
+}
+
+// levenshtein returns the minimum number of single-rune insertions, deletions
+// and substitutions needed to change a into b.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(first int, rest ...int) int {
+	for _, v := range rest {
+		if v < first {
+			first = v
+		}
+	}
+	return first
+}
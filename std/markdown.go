@@ -0,0 +1,127 @@
+package std
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// MarkdownCodeBlock produces the entire contents of the pipe wrapped in a
+// fenced Markdown code block, with lang (which may be empty) as its info
+// string. The fence is three backticks long, or longer if necessary, so that
+// any run of backticks within the contents can't close the block early. A
+// newline is added to the contents if they don't already end with one.
+func MarkdownCodeBlock(lang string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		data, err := io.ReadAll(p.Stdin)
+		if err != nil {
+			return p.Exit(err)
+		}
+		content := string(data)
+		fence := strings.Repeat("`", longestRun(content, '`')+1)
+		if len(fence) < 3 {
+			fence = "```"
+		}
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		return p.Fprint(fence + lang + "\n" + content + fence + "\n")
+	}
+	return p
+}
+
+// ToMarkdownTable reads rows of whitespace-delimited columns from the pipe,
+// one per line, and produces them as a GitHub-flavored Markdown table, using
+// the first row as the header. Cells are padded so that the columns line up,
+// and rows with fewer columns than the widest row are padded with empty
+// cells. Any "|" characters within cells are escaped. Empty lines are
+// ignored.
+func ToMarkdownTable() pipeline.Program {
+	return markdownTable(strings.Fields)
+}
+
+// ToMarkdownTableDelim is like [ToMarkdownTable], but columns are delimited
+// by the string delim, and surrounding whitespace is trimmed from each cell.
+func ToMarkdownTableDelim(delim string) pipeline.Program {
+	return markdownTable(func(line string) []string {
+		cells := strings.Split(line, delim)
+		for i := range cells {
+			cells[i] = strings.TrimSpace(cells[i])
+		}
+		return cells
+	})
+}
+
+func markdownTable(split func(string) []string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		var rows [][]string
+		var widths []int
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			if strings.TrimSpace(scanner.Text()) == "" {
+				continue
+			}
+			row := split(scanner.Text())
+			for i, cell := range row {
+				row[i] = strings.ReplaceAll(cell, "|", "\\|")
+				if i == len(widths) {
+					widths = append(widths, 3) // the minimum separator width
+				}
+				if n := utf8.RuneCountInString(row[i]); n > widths[i] {
+					widths[i] = n
+				}
+			}
+			rows = append(rows, row)
+		}
+		if err := scanner.Err(); err != nil {
+			return p.Exit(err)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		out := new(strings.Builder)
+		writeRow := func(row []string) {
+			out.WriteString("|")
+			for i, width := range widths {
+				cell := ""
+				if i < len(row) {
+					cell = row[i]
+				}
+				out.WriteString(" " + cell + strings.Repeat(" ", width-utf8.RuneCountInString(cell)) + " |")
+			}
+			out.WriteString("\n")
+		}
+		writeRow(rows[0])
+		separator := make([]string, len(widths))
+		for i, width := range widths {
+			separator[i] = strings.Repeat("-", width)
+		}
+		writeRow(separator)
+		for _, row := range rows[1:] {
+			writeRow(row)
+		}
+		return p.Fprint(out.String())
+	}
+	return p
+}
+
+// longestRun returns the length of the longest run of consecutive bytes c in
+// s.
+func longestRun(s string, c byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != c {
+			run = 0
+			continue
+		}
+		run++
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
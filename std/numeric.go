@@ -0,0 +1,280 @@
+package std
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// AggregateBy groups the lines of input by the value of column keyCol, where
+// the first column is column 1 and columns are delimited by Unicode
+// whitespace, and produces one line per group in the form
+// "<key> <aggregate>", sorted by key. The aggregate is computed over the
+// numeric values in column valCol of the group's lines, according to op,
+// which is one of "sum", "avg", "min", "max" or "count". Lines whose column
+// keyCol is missing are ignored, as are lines whose column valCol is missing
+// or isn't a finite number, except that "count" counts every line in the group. A
+// group with no numeric values has a sum of 0, and is omitted for "avg",
+// "min" and "max". If op isn't recognised, the pipe's error status will be
+// set.
+func AggregateBy(keyCol, valCol int, op string) pipeline.Program {
+	type group struct {
+		count, n      int
+		sum, min, max float64
+	}
+	p := pipeline.NewBaseProgram()
+	var err error
+	switch op {
+	case "sum", "avg", "min", "max", "count":
+	default:
+		err = fmt.Errorf("unknown aggregate operation %q", op)
+	}
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		groups := map[string]*group{}
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			line := scanner.Text()
+			columns := strings.Fields(line)
+			if keyCol < 1 || keyCol > len(columns) {
+				continue
+			}
+			g, ok := groups[columns[keyCol-1]]
+			if !ok {
+				g = &group{}
+				groups[columns[keyCol-1]] = g
+			}
+			g.count++
+			v, ok := numericColumn(line, valCol)
+			if !ok {
+				continue
+			}
+			if g.n == 0 || v < g.min {
+				g.min = v
+			}
+			if g.n == 0 || v > g.max {
+				g.max = v
+			}
+			g.sum += v
+			g.n++
+		}
+		if err := scanner.Err(); err != nil {
+			return p.Exit(err)
+		}
+		keys := make([]string, 0, len(groups))
+		for k := range groups {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			g := groups[k]
+			if g.n == 0 && op != "count" && op != "sum" {
+				continue
+			}
+			var agg float64
+			switch op {
+			case "sum":
+				agg = g.sum
+			case "avg":
+				agg = g.sum / float64(g.n)
+			case "min":
+				agg = g.min
+			case "max":
+				agg = g.max
+			case "count":
+				agg = float64(g.count)
+			}
+			fmt.Fprintln(p.Stdout, k, formatFloat(agg))
+		}
+		return nil
+	}
+	return p
+}
+
+// Bucketize reads numeric values from column col of each line of input, where
+// the first column is column 1 and columns are delimited by Unicode
+// whitespace, and counts them in fixed-width buckets of size bucketSize. It
+// produces one line per non-empty bucket, in ascending order, in the form
+// "<bucketStart>-<bucketEnd>: <count>", where each bucket includes its start
+// value but not its end value. Lines whose column col is missing or isn't a
+// finite number, such as NaN or Inf, are ignored. If bucketSize isn't
+// positive, the pipe's error status will be set.
+func Bucketize(col int, bucketSize float64) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	var err error
+	if !(bucketSize > 0) {
+		err = errors.New("bucket size must be positive")
+	}
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		counts := map[float64]int{}
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			v, ok := numericColumn(scanner.Text(), col)
+			if !ok {
+				continue
+			}
+			counts[math.Floor(v/bucketSize)]++
+		}
+		if err := scanner.Err(); err != nil {
+			return p.Exit(err)
+		}
+		buckets := make([]float64, 0, len(counts))
+		for b := range counts {
+			buckets = append(buckets, b)
+		}
+		sort.Float64s(buckets)
+		for _, b := range buckets {
+			fmt.Fprintf(p.Stdout, "%s-%s: %d\n", formatFloat(b*bucketSize), formatFloat((b+1)*bucketSize), counts[b])
+		}
+		return nil
+	}
+	return p
+}
+
+// DetectGaps reads integer values from column col of each line of input,
+// where the first column is column 1 and columns are delimited by Unicode
+// whitespace, and expects each value to be one more than the previous one.
+// Wherever a value jumps by more than 1, it produces a line giving the range
+// of missing values, in the form "<first>-<last>", or just "<first>" if a
+// single value is missing. Lines whose column col is missing or isn't an
+// integer are ignored, as are values that don't increase.
+func DetectGaps(col int) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		var prev int64
+		seen := false
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			columns := strings.Fields(scanner.Text())
+			if col < 1 || col > len(columns) {
+				continue
+			}
+			v, err := strconv.ParseInt(columns[col-1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch {
+			case seen && v == prev+2:
+				fmt.Fprintln(p.Stdout, prev+1)
+			case seen && v > prev+2:
+				fmt.Fprintf(p.Stdout, "%d-%d\n", prev+1, v-1)
+			}
+			if !seen || v > prev {
+				prev = v
+			}
+			seen = true
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// RunningMinMax reads numeric values from column col of each line of input,
+// where the first column is column 1 and columns are delimited by Unicode
+// whitespace, and produces each line followed by the minimum and maximum
+// values seen so far, separated by spaces. Lines whose column col is
+// missing or isn't a finite number are followed by the previous minimum and
+// maximum, or produced unchanged if there are none yet.
+func RunningMinMax(col int) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		var min, max float64
+		seen := false
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if v, ok := numericColumn(line, col); ok {
+				if !seen || v < min {
+					min = v
+				}
+				if !seen || v > max {
+					max = v
+				}
+				seen = true
+			}
+			if !seen {
+				fmt.Fprintln(p.Stdout, line)
+				continue
+			}
+			fmt.Fprintln(p.Stdout, line, formatFloat(min), formatFloat(max))
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// numericColumn returns the value of whitespace-delimited column col of line
+// parsed as a float64, and whether it could be parsed as a finite number.
+// NaN and infinities are rejected, since they can't be meaningfully summed,
+// compared or bucketed.
+func numericColumn(line string, col int) (float64, bool) {
+	columns := strings.Fields(line)
+	if col < 1 || col > len(columns) {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(columns[col-1], 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v, true
+}
+
+// rewriteColumn produces each line of input with column col rewritten by
+// fn, as described by replaceColumn.
+func rewriteColumn(col int, fn func(string) (string, bool)) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			fmt.Fprintln(p.Stdout, replaceColumn(scanner.Text(), col, fn))
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// replaceColumn returns line with its whitespace-delimited column col
+// replaced by the result of fn, leaving the surrounding whitespace intact. If
+// the column is missing, or fn reports false, line is returned unchanged.
+func replaceColumn(line string, col int, fn func(string) (string, bool)) string {
+	n := 0
+	start := -1
+	for i, r := range line + " " {
+		if !unicode.IsSpace(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start < 0 {
+			continue
+		}
+		n++
+		if n == col {
+			replacement, ok := fn(line[start:i])
+			if !ok {
+				return line
+			}
+			return line[:start] + replacement + line[i:]
+		}
+		start = -1
+	}
+	return line
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,49 @@
+package std
+
+import (
+	"fmt"
+	"io"
+	"plugin"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// PluginFilter loads the Go plugin at path (see [plugin.Open]) and filters
+// the contents of the pipe through the function it exports as Filter, which
+// must have the signature:
+//
+//	func Filter(r io.Reader, w io.Writer) error
+//
+// This allows transform logic to be built and shipped separately from the
+// main program. If the plugin can't be loaded, doesn't export Filter, or
+// Filter has the wrong signature, the pipe's error status will be set.
+// Plugins are only supported on some platforms; see the [plugin] package
+// documentation for details.
+func PluginFilter(path string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	filter, err := loadPluginFilter(path)
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		return filter(p.Stdin, p.Stdout)
+	}
+	return p
+}
+
+func loadPluginFilter(path string) (func(io.Reader, io.Writer) error, error) {
+	plug, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := plug.Lookup("Filter")
+	if err != nil {
+		return nil, err
+	}
+	filter, ok := sym.(func(io.Reader, io.Writer) error)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s: symbol Filter has type %T, want func(io.Reader, io.Writer) error", path, sym)
+	}
+	return filter, nil
+}
@@ -0,0 +1,74 @@
+package std
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// Sample produces up to n lines chosen at random from the pipe's contents,
+// with every line equally likely to be chosen. It uses reservoir sampling, so
+// only n lines are held in memory however large the input is. The chosen
+// lines aren't necessarily produced in their original order. If there are n
+// lines or fewer, all of them are produced. To get a reproducible sample, use
+// [SampleSeed].
+func Sample(n int) pipeline.Program {
+	return SampleSeed(n, time.Now().UnixNano())
+}
+
+// SampleSeed is like [Sample], but the lines are chosen using a random
+// number generator seeded with seed, so the same input and seed always
+// produce the same sample.
+func SampleSeed(n int, seed int64) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		rng := rand.New(rand.NewSource(seed))
+		var reservoir []string
+		scanner := newScanner(p.Stdin)
+		for i := 0; scanner.Scan(); i++ {
+			switch {
+			case len(reservoir) < n:
+				reservoir = append(reservoir, scanner.Text())
+			case n > 0:
+				if j := rng.Intn(i + 1); j < n {
+					reservoir[j] = scanner.Text()
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return p.Exit(err)
+		}
+		return writeLines(p, reservoir)
+	}
+	return p
+}
+
+// Shuffle produces the lines of the pipe's contents in a random order, with
+// every permutation equally likely, using the Fisher–Yates shuffle. Like
+// [Sort] and [Reverse], Shuffle reads all of its input before producing any
+// output, so it holds the entire contents of the pipe in memory. To get a
+// reproducible order, use [ShuffleSeed].
+func Shuffle() pipeline.Program {
+	return ShuffleSeed(time.Now().UnixNano())
+}
+
+// ShuffleSeed is like [Shuffle], but the order is chosen using a random
+// number generator seeded with seed, so the same input and seed always
+// produce the same order.
+func ShuffleSeed(seed int64) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		lines, err := readLines(p.Stdin)
+		if err != nil {
+			return p.Exit(err)
+		}
+		rng := rand.New(rand.NewSource(seed))
+		for i := len(lines) - 1; i > 0; i-- {
+			j := rng.Intn(i + 1)
+			lines[i], lines[j] = lines[j], lines[i]
+		}
+		return writeLines(p, lines)
+	}
+	return p
+}
@@ -0,0 +1,30 @@
+package std
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// ReplaceN is like [std.Replace], but replaces only the first n occurrences
+// of the string search in each line, like sed without the g flag when n is
+// 1. If n is negative, all occurrences are replaced, as for Replace.
+func ReplaceN(search, replace string, n int) pipeline.Program {
+	return pipeline.Scanner(func(line string, w io.Writer) {
+		fmt.Fprintln(w, strings.Replace(line, search, replace, n))
+	})
+}
+
+// ReplaceRegexpFunc is like [std.ReplaceRegexp], but replaces each match of
+// the compiled regexp re in each line with the result of calling repl with
+// the full text of the match, as for [regexp.Regexp.ReplaceAllStringFunc].
+// Submatches aren't passed to repl; it can use re.FindStringSubmatch on the
+// match to extract them if necessary.
+func ReplaceRegexpFunc(re *regexp.Regexp, repl func(string) string) pipeline.Program {
+	return pipeline.Scanner(func(line string, w io.Writer) {
+		fmt.Fprintln(w, re.ReplaceAllStringFunc(line, repl))
+	})
+}
@@ -0,0 +1,73 @@
+package std
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// RequireLineCount copies the pipe's contents unchanged to its output, but
+// sets the pipe's error status if the number of lines is less than min, or
+// greater than max. If max is zero or negative, there is no upper bound. A
+// final line that isn't terminated by a newline is included in the count.
+// As the count is only known once the input is exhausted, the error is set
+// after all the contents have been produced.
+func RequireLineCount(min, max int) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		lines, partial := 0, false
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := p.Stdin.Read(buf)
+			if n > 0 {
+				lines += bytes.Count(buf[:n], []byte{'\n'})
+				partial = buf[n-1] != '\n'
+				if _, err := p.Stdout.Write(buf[:n]); err != nil {
+					return p.Exit(err)
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return p.Exit(err)
+			}
+		}
+		if partial {
+			lines++
+		}
+		if lines < min {
+			return p.SetError(fmt.Errorf("got %d lines, want at least %d", lines, min))
+		}
+		if max > 0 && lines > max {
+			return p.SetError(fmt.Errorf("got %d lines, want at most %d", lines, max))
+		}
+		return nil
+	}
+	return p
+}
+
+// RequireNonEmpty copies the pipe's contents unchanged to its output, but
+// sets the pipe's error status to an error with the message msg if the input
+// is empty, that is, contains no bytes at all. This guards against a command
+// silently producing no output.
+func RequireNonEmpty(msg string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		r := bufio.NewReader(p.Stdin)
+		if _, err := r.Peek(1); err == io.EOF {
+			return p.SetError(errors.New(msg))
+		} else if err != nil {
+			return p.Exit(err)
+		}
+		if _, err := io.Copy(p.Stdout, r); err != nil {
+			return p.Exit(err)
+		}
+		return nil
+	}
+	return p
+}
@@ -0,0 +1,110 @@
+package std
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// NewSince compares the distinct lines of the pipe's contents with those in
+// the snapshot file snapshotPath, and produces only the lines that aren't in
+// the snapshot, in the order they first appear in the input. The snapshot is
+// then replaced with the current distinct lines, so that the next run reports
+// only lines that have appeared since. If the snapshot doesn't exist, every
+// line is new, and the snapshot is created.
+func NewSince(snapshotPath string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		previous, current, err := readSnapshot(p, snapshotPath)
+		if err != nil {
+			return p.Exit(err)
+		}
+		seen := lineSet(previous)
+		var added []string
+		for _, line := range current {
+			if !seen[line] {
+				added = append(added, line)
+			}
+		}
+		if err := writeLines(p, added); err != nil {
+			return err
+		}
+		return writeSnapshot(p, snapshotPath, current)
+	}
+	return p
+}
+
+// RemovedSince is the complement of [NewSince]: it produces the lines in the
+// snapshot file snapshotPath that aren't among the lines of the pipe's
+// contents, in snapshot order, and then replaces the snapshot with the
+// current distinct lines. If the snapshot doesn't exist, nothing has been
+// removed, and the snapshot is created.
+func RemovedSince(snapshotPath string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		previous, current, err := readSnapshot(p, snapshotPath)
+		if err != nil {
+			return p.Exit(err)
+		}
+		seen := lineSet(current)
+		var removed []string
+		for _, line := range previous {
+			if !seen[line] {
+				removed = append(removed, line)
+			}
+		}
+		if err := writeLines(p, removed); err != nil {
+			return err
+		}
+		return writeSnapshot(p, snapshotPath, current)
+	}
+	return p
+}
+
+// readSnapshot returns the lines in the snapshot file path, which are empty if
+// it doesn't exist, and the distinct lines of the pipe's contents.
+func readSnapshot(p *pipeline.BaseProgram, path string) (previous, current []string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+	if len(data) > 0 {
+		previous = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+	lines, err := readLines(p.Stdin)
+	if err != nil {
+		return nil, nil, err
+	}
+	seen := map[string]bool{}
+	for _, line := range lines {
+		if !seen[line] {
+			seen[line] = true
+			current = append(current, line)
+		}
+	}
+	return previous, current, nil
+}
+
+// writeSnapshot replaces the snapshot file path with lines, setting the pipe's
+// error status if it can't be written.
+func writeSnapshot(p *pipeline.BaseProgram, path string, lines []string) error {
+	data := ""
+	if len(lines) > 0 {
+		data = strings.Join(lines, "\n") + "\n"
+	}
+	if err := os.WriteFile(path, []byte(data), 0o666); err != nil {
+		return p.Exit(err)
+	}
+	return nil
+}
+
+func lineSet(lines []string) map[string]bool {
+	set := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		set[line] = true
+	}
+	return set
+}
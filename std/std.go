@@ -0,0 +1,893 @@
+// Package std provides additional programs for script pipelines,
+// complementing those in [github.com/bartdeboer/pipeline/std].
+package std
+
+import (
+	"bufio"
+	"bytes"
+	"container/ring"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// ColumnDelim produces column col of each line of input, where the first
+// column is column 1, and columns are delimited by the string delim. Unlike
+// Column, consecutive delimiters are not collapsed, so empty fields are
+// preserved. Lines containing fewer than col columns will be skipped.
+func ColumnDelim(col int, delim string) pipeline.Program {
+	return pipeline.Scanner(func(line string, w io.Writer) {
+		columns := strings.Split(line, delim)
+		if col > 0 && col <= len(columns) {
+			fmt.Fprintln(w, columns[col-1])
+		}
+	})
+}
+
+// Cut produces the fields of each line of input selected by ranges, where
+// fields are delimited by the string delim, rejoined by delim, like
+// cut -d delim -f ranges. ranges is a comma-separated list of 1-indexed
+// field numbers and ranges, for example "1,3-5,7-": "N" selects field N,
+// "N-M" selects fields N to M inclusive, "N-" selects field N to the end of
+// the line, and "-M" selects fields 1 to M. Fields are produced in input
+// order, each at most once. Selected fields beyond the end of a line are
+// ignored, and lines containing none of the selected fields are skipped. An
+// invalid ranges specification sets the pipe's error status.
+func Cut(ranges string, delim string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	selected, err := parseRanges(ranges)
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			var out []string
+			for i, field := range strings.Split(scanner.Text(), delim) {
+				if selected(i + 1) {
+					out = append(out, field)
+				}
+			}
+			if len(out) > 0 {
+				fmt.Fprintln(p.Stdout, strings.Join(out, delim))
+			}
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// parseRanges parses a cut(1)-style list of field ranges, returning a
+// function reporting whether a given 1-indexed field is selected.
+func parseRanges(spec string) (func(int) bool, error) {
+	type fieldRange struct{ from, to int } // to == 0 means unbounded
+	var ranges []fieldRange
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("invalid field range %q", spec)
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		r := fieldRange{1, 0}
+		var err error
+		if from != "" {
+			if r.from, err = strconv.Atoi(from); err != nil || r.from < 1 {
+				return nil, fmt.Errorf("invalid field range %q", part)
+			}
+		}
+		switch {
+		case !isRange:
+			r.to = r.from
+		case to != "":
+			if r.to, err = strconv.Atoi(to); err != nil || r.to < r.from {
+				return nil, fmt.Errorf("invalid field range %q", part)
+			}
+		case from == "":
+			return nil, fmt.Errorf("invalid field range %q", part)
+		}
+		ranges = append(ranges, r)
+	}
+	return func(field int) bool {
+		for _, r := range ranges {
+			if field >= r.from && (r.to == 0 || field <= r.to) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// FirstByColumn produces each line of input whose column col, where the
+// first column is column 1 and columns are delimited by Unicode whitespace,
+// has a value not seen on any previous line. Later lines repeating a value
+// are dropped, and lines with fewer than col columns are skipped. Lines are
+// produced as they are read, in their original order, and only the distinct
+// values are kept in memory.
+func FirstByColumn(col int) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		seen := map[string]bool{}
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			line := scanner.Text()
+			columns := strings.Fields(line)
+			if col < 1 || col > len(columns) {
+				continue
+			}
+			key := columns[col-1]
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			fmt.Fprintln(p.Stdout, line)
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// FirstMatchEach produces the first line of input containing each of
+// patterns, in the order they occur, so that at most one line is produced
+// per pattern. A line containing several patterns that haven't yet been
+// matched is produced once, and counts for all of them. Once every pattern
+// has been matched, no more input is read.
+func FirstMatchEach(patterns ...string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		pending := append([]string(nil), patterns...)
+		scanner := newScanner(p.Stdin)
+		for len(pending) > 0 && scanner.Scan() {
+			line := scanner.Text()
+			remaining := pending[:0]
+			for _, pattern := range pending {
+				if !strings.Contains(line, pattern) {
+					remaining = append(remaining, pattern)
+				}
+			}
+			if len(remaining) < len(pending) {
+				fmt.Fprintln(p.Stdout, line)
+			}
+			pending = remaining
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// FreqCSV produces only the unique lines from the pipe's contents, each
+// prefixed with a frequency count, in descending numerical order, like Freq.
+// Instead of a padded column, each line is emitted as a "count,value" CSV
+// record, quoting values where necessary (for example, values containing
+// commas or quotes).
+func FreqCSV() pipeline.Program {
+	freq := map[string]int{}
+	type frequency struct {
+		line  string
+		count int
+	}
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			freq[scanner.Text()]++
+		}
+		if err := scanner.Err(); err != nil {
+			return p.Exit(err)
+		}
+		freqs := make([]frequency, 0, len(freq))
+		for line, count := range freq {
+			freqs = append(freqs, frequency{line, count})
+		}
+		sort.Slice(freqs, func(i, j int) bool {
+			x, y := freqs[i].count, freqs[j].count
+			if x == y {
+				return freqs[i].line < freqs[j].line
+			}
+			return x > y
+		})
+		w := csv.NewWriter(p.Stdout)
+		for _, item := range freqs {
+			if err := w.Write([]string{strconv.Itoa(item.count), item.line}); err != nil {
+				return p.Exit(err)
+			}
+		}
+		w.Flush()
+		return w.Error()
+	}
+	return p
+}
+
+// MatchContext produces the input lines that contain the string s, together
+// with up to before lines preceding and after lines following each match,
+// like grep -B before -A after. Groups of lines that aren't contiguous in the
+// input are separated by a line containing "--", as in GNU grep. Only the
+// last before lines are held in memory.
+func MatchContext(s string, before, after int) pipeline.Program {
+	return matchContext(func(line string) bool {
+		return strings.Contains(line, s)
+	}, before, after)
+}
+
+// MatchFold produces only the input lines that contain the string s, ignoring
+// case. Comparison uses Unicode simple case folding, as for
+// [strings.EqualFold], so that for example "σίσυφος" matches "ΣΊΣΥΦΟΣ", and
+// "k" matches the Kelvin sign (U+212A): folding is not limited to ASCII.
+func MatchFold(s string) pipeline.Program {
+	return pipeline.Scanner(func(line string, w io.Writer) {
+		if containsFold(line, s) {
+			fmt.Fprintln(w, line)
+		}
+	})
+}
+
+// RejectFold produces only the input lines that do not contain the string s,
+// ignoring case, as for [MatchFold].
+func RejectFold(s string) pipeline.Program {
+	return pipeline.Scanner(func(line string, w io.Writer) {
+		if !containsFold(line, s) {
+			fmt.Fprintln(w, line)
+		}
+	})
+}
+
+// containsFold reports whether substr is within s under Unicode simple case
+// folding.
+func containsFold(s, substr string) bool {
+	n := utf8.RuneCountInString(substr)
+	for i := range s {
+		end := i
+		for j := 0; j < n; j++ {
+			if end >= len(s) {
+				return false
+			}
+			_, size := utf8.DecodeRuneInString(s[end:])
+			end += size
+		}
+		if strings.EqualFold(s[i:end], substr) {
+			return true
+		}
+	}
+	return substr == ""
+}
+
+// MatchRegexpContext is like [MatchContext], but produces the input lines
+// that match the compiled regexp re, with their surrounding context.
+func MatchRegexpContext(re *regexp.Regexp, before, after int) pipeline.Program {
+	return matchContext(re.MatchString, before, after)
+}
+
+func matchContext(match func(string) bool, before, after int) pipeline.Program {
+	type numberedLine struct {
+		n    int
+		text string
+	}
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		scanner := newScanner(p.Stdin)
+		var buffered []numberedLine
+		lastPrinted := -1
+		afterLeft := 0
+		emit := func(n int, text string) {
+			if lastPrinted >= 0 && n > lastPrinted+1 {
+				fmt.Fprintln(p.Stdout, "--")
+			}
+			fmt.Fprintln(p.Stdout, text)
+			lastPrinted = n
+		}
+		for n := 0; scanner.Scan(); n++ {
+			line := scanner.Text()
+			switch {
+			case match(line):
+				for _, b := range buffered {
+					if b.n > lastPrinted {
+						emit(b.n, b.text)
+					}
+				}
+				buffered = buffered[:0]
+				emit(n, line)
+				afterLeft = after
+			case afterLeft > 0:
+				emit(n, line)
+				afterLeft--
+			case before > 0:
+				if len(buffered) == before {
+					copy(buffered, buffered[1:])
+					buffered = buffered[:before-1]
+				}
+				buffered = append(buffered, numberedLine{n, line})
+			}
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// NumberLines produces each line of input prefixed with its line number,
+// starting at 1, and a tab, like cat -n. Line numbers are right-justified
+// and padded with spaces to a width of six characters, growing as necessary
+// for longer numbers.
+func NumberLines() pipeline.Program {
+	return NumberLinesFrom(1)
+}
+
+// NumberLinesFrom is like [NumberLines], but numbers lines starting at start,
+// which is useful for continuing the numbering across several inputs.
+func NumberLinesFrom(start int) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		scanner := newScanner(p.Stdin)
+		for n := start; scanner.Scan(); n++ {
+			fieldWidth := len(strconv.Itoa(n))
+			if fieldWidth < 6 {
+				fieldWidth = 6
+			}
+			if _, err := fmt.Fprintf(p.Stdout, "%*d\t%s\n", fieldWidth, n, scanner.Text()); err != nil {
+				return p.Exit(err)
+			}
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// WithOffsets produces each line of the pipe's contents prefixed with the
+// byte offset at which it starts in the input, counting from zero, and a tab.
+// The offsets count every byte of the input, including line endings, so
+// they can be used to seek directly to a line in the original file.
+func WithOffsets() pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		var offset, next int64
+		scanner := newScanner(p.Stdin)
+		scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			advance, token, err := bufio.ScanLines(data, atEOF)
+			if token != nil {
+				offset = next
+			}
+			next += int64(advance)
+			return advance, token, err
+		})
+		for scanner.Scan() {
+			if _, err := fmt.Fprintf(p.Stdout, "%d\t%s\n", offset, scanner.Text()); err != nil {
+				return p.Exit(err)
+			}
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// Reverse produces the lines of the pipe's contents in reverse order, like
+// Unix tac(1). Each line is produced unchanged, followed by a newline. Like
+// Last, Reverse necessarily reads all of its input before producing any
+// output, so it holds the entire contents of the pipe in memory.
+func Reverse() pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		lines, err := readLines(p.Stdin)
+		if err != nil {
+			return p.Exit(err)
+		}
+		for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+			lines[i], lines[j] = lines[j], lines[i]
+		}
+		return writeLines(p, lines)
+	}
+	return p
+}
+
+// Sort produces the lines of the pipe's contents sorted in lexical order, like
+// Unix sort(1). Sort reads all of its input before producing any output.
+func Sort() pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		lines, err := readLines(p.Stdin)
+		if err != nil {
+			return p.Exit(err)
+		}
+		sort.Strings(lines)
+		return writeLines(p, lines)
+	}
+	return p
+}
+
+// SortNumeric produces the lines of the pipe's contents sorted in ascending
+// numerical order, like sort -n. Each line is trimmed of surrounding
+// whitespace and parsed as a float64, so negative numbers and scientific
+// notation are supported. Lines that can't be parsed are produced last, in
+// their original order. SortNumeric reads all of its input before producing
+// any output.
+func SortNumeric() pipeline.Program {
+	return sortNumeric(false)
+}
+
+// SortReverse produces the lines of the pipe's contents sorted in descending
+// numerical order, like sort -rn. Lines that can't be parsed as numbers are
+// produced last, in their original order, as for [SortNumeric].
+func SortReverse() pipeline.Program {
+	return sortNumeric(true)
+}
+
+func sortNumeric(reverse bool) pipeline.Program {
+	type numericLine struct {
+		line  string
+		value float64
+		ok    bool
+	}
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		lines, err := readLines(p.Stdin)
+		if err != nil {
+			return p.Exit(err)
+		}
+		items := make([]numericLine, len(lines))
+		for i, line := range lines {
+			v, err := strconv.ParseFloat(strings.TrimSpace(line), 64)
+			items[i] = numericLine{line, v, err == nil}
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			x, y := items[i], items[j]
+			if !x.ok || !y.ok {
+				return x.ok && !y.ok
+			}
+			if reverse {
+				return x.value > y.value
+			}
+			return x.value < y.value
+		})
+		for i, item := range items {
+			lines[i] = item.line
+		}
+		return writeLines(p, lines)
+	}
+	return p
+}
+
+// Head produces the first n lines of the pipe's contents, like First, but
+// also sets the pipe's error status if fewer than n lines were available, so
+// that a caller can tell the output was cut short. As with First, no more of
+// the input is read than is needed. If n is zero or negative, there is no
+// output and no error.
+func Head(n int) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		if n <= 0 {
+			return nil
+		}
+		lines := 0
+		scanner := newScanner(p.Stdin)
+		for lines < n && scanner.Scan() {
+			if _, err := fmt.Fprintln(p.Stdout, scanner.Text()); err != nil {
+				return p.Exit(err)
+			}
+			lines++
+		}
+		if err := scanner.Err(); err != nil {
+			return p.Exit(err)
+		}
+		if lines < n {
+			return p.SetError(fmt.Errorf("got %d lines, want %d", lines, n))
+		}
+		return nil
+	}
+	return p
+}
+
+// Skip discards the first n lines of the pipe's contents and produces the
+// rest, like tail -n +N with N one more than n. This is useful for dropping
+// a header line. If n is zero or negative, all the lines are produced.
+func Skip(n int) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		scanner := newScanner(p.Stdin)
+		for i := 0; scanner.Scan(); i++ {
+			if i < n {
+				continue
+			}
+			if _, err := fmt.Fprintln(p.Stdout, scanner.Text()); err != nil {
+				return p.Exit(err)
+			}
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// SkipLast produces all but the last n lines of the pipe's contents, like
+// head -n -N. Only the most recent n lines are held in memory, in a ring
+// buffer, and each line is produced as soon as it's known not to be one of
+// the last n. If there are n lines or fewer, there is no output at all. If n
+// is zero or negative, all the lines are produced.
+func SkipLast(n int) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		scanner := newScanner(p.Stdin)
+		if n <= 0 {
+			for scanner.Scan() {
+				if _, err := fmt.Fprintln(p.Stdout, scanner.Text()); err != nil {
+					return p.Exit(err)
+				}
+			}
+			return scanner.Err()
+		}
+		held := ring.New(n)
+		for scanner.Scan() {
+			if held.Value != nil {
+				if _, err := fmt.Fprintln(p.Stdout, held.Value); err != nil {
+					return p.Exit(err)
+				}
+			}
+			held.Value = scanner.Text()
+			held = held.Next()
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// SplitEach splits the pipe's contents into chunks separated by delim, and
+// calls fn with a reader for each chunk in turn and the program's output. The
+// delimiters themselves aren't passed to fn, and a final delimiter at the end
+// of the input doesn't produce an empty chunk. If delim is empty, or fn
+// returns an error, the pipe's error status will be set.
+func SplitEach(delim string, fn func(chunk io.Reader, w io.Writer) error) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	var err error
+	if delim == "" {
+		err = fmt.Errorf("empty delimiter")
+	}
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		scanner := newScanner(p.Stdin)
+		scanner.Split(splitOn([]byte(delim)))
+		for scanner.Scan() {
+			if err := fn(bytes.NewReader(scanner.Bytes()), p.Stdout); err != nil {
+				return p.Exit(err)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return p.Exit(err)
+		}
+		return nil
+	}
+	return p
+}
+
+// TeeLineCount copies the pipe's contents unchanged to its output, calling
+// fn with the cumulative number of lines seen after every n lines, and once
+// more with the final count when the input is exhausted. A final line that
+// isn't terminated by a newline is included in the count. If n is zero or
+// negative, fn is only called on completion. fn is always called from the
+// same goroutine, one call at a time.
+func TeeLineCount(n int, fn func(lines int)) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		lines := 0
+		partial := false
+		buf := make([]byte, 32*1024)
+		for {
+			nr, err := p.Stdin.Read(buf)
+			if nr > 0 {
+				for _, b := range buf[:nr] {
+					if b != '\n' {
+						continue
+					}
+					lines++
+					if n > 0 && lines%n == 0 {
+						fn(lines)
+					}
+				}
+				partial = buf[nr-1] != '\n'
+				if _, err := p.Stdout.Write(buf[:nr]); err != nil {
+					return p.Exit(err)
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return p.Exit(err)
+			}
+		}
+		if partial {
+			lines++
+		}
+		fn(lines)
+		return nil
+	}
+	return p
+}
+
+// ByteRange skips the first offset bytes of the pipe's contents, and then
+// produces the next length bytes, like dd with skip and count, without
+// reading the whole input into memory. If length is negative, everything
+// after offset is produced. If the input ends before offset, or before
+// length bytes have been produced, there's no error; the output is just
+// shorter. A negative offset is treated as zero.
+func ByteRange(offset, length int64) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		if offset > 0 {
+			if _, err := io.CopyN(io.Discard, p.Stdin, offset); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return p.Exit(err)
+			}
+		}
+		var err error
+		if length < 0 {
+			_, err = io.Copy(p.Stdout, p.Stdin)
+		} else {
+			_, err = io.CopyN(p.Stdout, p.Stdin, length)
+		}
+		if err != nil && err != io.EOF {
+			return p.Exit(err)
+		}
+		return nil
+	}
+	return p
+}
+
+// Truncate produces at most the first n bytes of the pipe's contents, and
+// then stops reading its input. This is a raw byte limit, intended as a
+// safety cap on output size, so the cut may fall in the middle of a line or
+// a multi-byte UTF-8 sequence; no error is reported when the input is
+// truncated. If n is zero or negative, there is no output at all.
+func Truncate(n int64) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		if n <= 0 {
+			return nil
+		}
+		_, err := io.Copy(p.Stdout, io.LimitReader(p.Stdin, n))
+		return err
+	}
+	return p
+}
+
+// WrapOn produces the lines of the pipe's contents, breaking any line longer
+// than maxLen bytes after the last occurrence of sep that fits within the
+// limit, so that words or fields separated by sep aren't split. For example,
+// with sep "," a long comma-separated list is wrapped after a comma. Spaces
+// and tabs around each break are removed. If there's no sep within the
+// limit, the line is broken after the first sep beyond it instead, or left
+// as it is if there's none. If maxLen is less than 1, or sep is empty, the
+// pipe's error status will be set.
+func WrapOn(maxLen int, sep string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	var err error
+	if maxLen < 1 {
+		err = fmt.Errorf("invalid line length %d: must be at least 1", maxLen)
+	} else if sep == "" {
+		err = fmt.Errorf("empty separator")
+	}
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			for _, line := range wrapOn(scanner.Text(), maxLen, sep) {
+				if _, err := fmt.Fprintln(p.Stdout, line); err != nil {
+					return p.Exit(err)
+				}
+			}
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+func wrapOn(line string, maxLen int, sep string) []string {
+	var lines []string
+	for len(line) > maxLen {
+		i := strings.LastIndex(line[:maxLen], sep)
+		if i < 0 {
+			i = strings.Index(line[maxLen:], sep)
+			if i < 0 {
+				break
+			}
+			i += maxLen
+		}
+		end := i + len(sep)
+		if end == len(line) {
+			break
+		}
+		if wrapped := strings.TrimRight(line[:end], " \t"); wrapped != "" {
+			lines = append(lines, wrapped)
+		}
+		line = strings.TrimLeft(line[end:], " \t")
+	}
+	return append(lines, line)
+}
+
+// Dedup produces each distinct line of the pipe's contents exactly once, the
+// first time it appears, like awk '!seen[$0]++'. Unlike [Uniq], duplicates
+// needn't be adjacent, so the input needn't be sorted, and unlike Freq, the
+// original order is preserved and output is streamed. Every distinct line is
+// held in memory, so memory use grows with the number of distinct lines,
+// without bound.
+func Dedup() pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		seen := map[string]bool{}
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if seen[line] {
+				continue
+			}
+			seen[line] = true
+			if _, err := fmt.Fprintln(p.Stdout, line); err != nil {
+				return p.Exit(err)
+			}
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// Uniq produces the lines of the pipe's contents, omitting any line that is
+// identical to the line immediately preceding it, like Unix uniq(1). Unlike
+// Freq, it doesn't buffer its input, so it's suitable for large inputs that
+// are already sorted.
+func Uniq() pipeline.Program {
+	return uniq(false)
+}
+
+// UniqCount is like [Uniq], but prefixes each line with the number of
+// adjacent times it occurred, like uniq -c. As the output is streamed, the
+// counts are right-justified in a fixed-width column of seven characters.
+func UniqCount() pipeline.Program {
+	return uniq(true)
+}
+
+func uniq(count bool) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		scanner := newScanner(p.Stdin)
+		var prev string
+		n := 0
+		emit := func() error {
+			if n == 0 {
+				return nil
+			}
+			var err error
+			if count {
+				_, err = fmt.Fprintf(p.Stdout, "%7d %s\n", n, prev)
+			} else {
+				_, err = fmt.Fprintln(p.Stdout, prev)
+			}
+			return err
+		}
+		for scanner.Scan() {
+			line := scanner.Text()
+			if n > 0 && line == prev {
+				n++
+				continue
+			}
+			if err := emit(); err != nil {
+				return p.Exit(err)
+			}
+			prev, n = line, 1
+		}
+		if err := scanner.Err(); err != nil {
+			return p.Exit(err)
+		}
+		if err := emit(); err != nil {
+			return p.Exit(err)
+		}
+		return nil
+	}
+	return p
+}
+
+// WordFreq splits the input into words separated by Unicode whitespace, and
+// produces each distinct word prefixed with the number of times it occurs,
+// in descending numerical order, like [github.com/bartdeboer/pipeline/std.Freq]
+// does for lines. If fold is true, words are compared after converting them
+// to lower case. If trimPunct is true, leading and trailing punctuation is
+// removed from each word, and words consisting only of punctuation are
+// ignored.
+func WordFreq(fold, trimPunct bool) pipeline.Program {
+	type frequency struct {
+		word  string
+		count int
+	}
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		freq := map[string]int{}
+		scanner := newScanner(p.Stdin)
+		scanner.Split(bufio.ScanWords)
+		for scanner.Scan() {
+			word := scanner.Text()
+			if trimPunct {
+				word = strings.TrimFunc(word, unicode.IsPunct)
+				if word == "" {
+					continue
+				}
+			}
+			if fold {
+				word = strings.ToLower(word)
+			}
+			freq[word]++
+		}
+		if err := scanner.Err(); err != nil {
+			return p.Exit(err)
+		}
+		freqs := make([]frequency, 0, len(freq))
+		max := 0
+		for word, count := range freq {
+			freqs = append(freqs, frequency{word, count})
+			if count > max {
+				max = count
+			}
+		}
+		sort.Slice(freqs, func(i, j int) bool {
+			x, y := freqs[i].count, freqs[j].count
+			if x == y {
+				return freqs[i].word < freqs[j].word
+			}
+			return x > y
+		})
+		fieldWidth := len(strconv.Itoa(max))
+		for _, item := range freqs {
+			fmt.Fprintf(p.Stdout, "%*d %s\n", fieldWidth, item.count, item.word)
+		}
+		return nil
+	}
+	return p
+}
+
+func readLines(r io.Reader) ([]string, error) {
+	lines := []string{}
+	scanner := newScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+func writeLines(p *pipeline.BaseProgram, lines []string) error {
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(p.Stdout, line); err != nil {
+			return p.Exit(err)
+		}
+	}
+	return nil
+}
+
+// splitOn returns a split function for a [bufio.Scanner] producing the data
+// between occurrences of delim
+func splitOn(delim []byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.Index(data, delim); i >= 0 {
+			return i + len(delim), data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+func newScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), math.MaxInt)
+	return scanner
+}
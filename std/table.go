@@ -0,0 +1,188 @@
+package std
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// JoinOn performs an inner join of the input with right. It reads all of
+// right first, indexing each line by its column rightCol, and then produces,
+// for each line of input whose column leftCol matches that of a line of
+// right, the input line followed by sep and the matching right line. If
+// several lines of right match, each is produced in turn, in the order they
+// appear in right. Columns are numbered from 1 and delimited by Unicode
+// whitespace, and lines with too few columns never match.
+//
+// If right has an Error method, as pipes do, and it returns a non-nil error
+// once right has been read, the pipe's error status is set to that error.
+func JoinOn(right io.Reader, leftCol, rightCol int, sep string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		lines, err := readLines(right)
+		if err == nil {
+			if e, ok := right.(interface{ Error() error }); ok {
+				err = e.Error()
+			}
+		}
+		if err != nil {
+			return p.Exit(err)
+		}
+		index := map[string][]string{}
+		for _, line := range lines {
+			if key, ok := column(line, rightCol); ok {
+				index[key] = append(index[key], line)
+			}
+		}
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			line := scanner.Text()
+			key, ok := column(line, leftCol)
+			if !ok {
+				continue
+			}
+			for _, match := range index[key] {
+				fmt.Fprintln(p.Stdout, line+sep+match)
+			}
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// SubtractColumn produces only the lines of input whose column col isn't
+// among the keys in the file path, one per line, like an anti-join. The keys
+// are read into memory once, with leading and trailing whitespace removed,
+// before any input is read. Columns are numbered from 1 and delimited by
+// Unicode whitespace, and lines with too few columns are always produced. If
+// path can't be read, the pipe's error status will be set.
+func SubtractColumn(col int, path string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		f, err := os.Open(path)
+		if err != nil {
+			return p.Exit(err)
+		}
+		keys, err := readLines(f)
+		f.Close()
+		if err != nil {
+			return p.Exit(err)
+		}
+		exclude := make(map[string]bool, len(keys))
+		for _, key := range keys {
+			exclude[strings.TrimSpace(key)] = true
+		}
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if key, ok := column(line, col); ok && exclude[key] {
+				continue
+			}
+			if _, err := fmt.Fprintln(p.Stdout, line); err != nil {
+				return p.Exit(err)
+			}
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// column returns whitespace-delimited column col of line, and whether it
+// exists.
+func column(line string, col int) (string, bool) {
+	columns := strings.Fields(line)
+	if col < 1 || col > len(columns) {
+		return "", false
+	}
+	return columns[col-1], true
+}
+
+// SelectColumns reads the input as a table, whose first line is a header
+// naming each column, and columns are delimited by Unicode whitespace. It
+// produces the columns named by headers, in that order, for every line
+// including the header, separated by single spaces. Lines with too few
+// columns are skipped. If any of headers isn't found in the header line, the
+// pipe's error status is set.
+func SelectColumns(headers ...string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		scanner := newScanner(p.Stdin)
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+		index, err := columnIndexes(strings.Fields(scanner.Text()), headers)
+		if err != nil {
+			return p.Exit(err)
+		}
+		fmt.Fprintln(p.Stdout, strings.Join(headers, " "))
+		selected := make([]string, len(index))
+		for scanner.Scan() {
+			columns := strings.Fields(scanner.Text())
+			ok := true
+			for i, col := range index {
+				if col >= len(columns) {
+					ok = false
+					break
+				}
+				selected[i] = columns[col]
+			}
+			if ok {
+				fmt.Fprintln(p.Stdout, strings.Join(selected, " "))
+			}
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// WhereColumn reads the input as a table, whose first line is a header
+// naming each column, and columns are delimited by Unicode whitespace. It
+// produces the header line, followed by each line whose column named header
+// has a value for which predicate returns true. Lines are produced
+// unchanged, and lines with too few columns are skipped. If header isn't
+// found in the header line, the pipe's error status is set.
+func WhereColumn(header string, predicate func(string) bool) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		scanner := newScanner(p.Stdin)
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+		index, err := columnIndexes(strings.Fields(scanner.Text()), []string{header})
+		if err != nil {
+			return p.Exit(err)
+		}
+		fmt.Fprintln(p.Stdout, scanner.Text())
+		col := index[0]
+		for scanner.Scan() {
+			line := scanner.Text()
+			columns := strings.Fields(line)
+			if col < len(columns) && predicate(columns[col]) {
+				fmt.Fprintln(p.Stdout, line)
+			}
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+// columnIndexes returns the index in header of each of names.
+func columnIndexes(header []string, names []string) ([]int, error) {
+	index := make([]int, len(names))
+	for i, name := range names {
+		index[i] = -1
+		for j, h := range header {
+			if h == name {
+				index[i] = j
+				break
+			}
+		}
+		if index[i] < 0 {
+			return nil, fmt.Errorf("column %q not found", name)
+		}
+	}
+	return index, nil
+}
@@ -0,0 +1,54 @@
+package std
+
+import (
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// TemplateData is the data a template is executed with by [Template].
+type TemplateData struct {
+	// Text is the entire contents of the pipe.
+	Text string
+	// Lines is the contents of the pipe split into lines, without their
+	// trailing newlines.
+	Lines []string
+}
+
+// String returns the entire contents of the pipe, so that {{.}} produces
+// them in a template.
+func (d TemplateData) String() string {
+	return d.Text
+}
+
+// Template reads the entire contents of the pipe and produces the result of
+// executing the Go template tmpl, as for [text/template], with a
+// [TemplateData] holding the contents. In the template, {{.}} produces the
+// contents as they are, and {{range .Lines}} iterates over their lines. If
+// tmpl can't be parsed or executed, the pipe's error status will be set.
+func Template(tmpl string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	t, err := template.New("").Parse(tmpl)
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(p.Stdin)
+		if err != nil {
+			return p.Exit(err)
+		}
+		text := string(data)
+		var lines []string
+		if text != "" {
+			lines = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+		}
+		if err := t.Execute(p.Stdout, TemplateData{Text: text, Lines: lines}); err != nil {
+			return p.Exit(err)
+		}
+		return nil
+	}
+	return p
+}
@@ -0,0 +1,32 @@
+package std
+
+import (
+	"strings"
+
+	"github.com/bartdeboer/pipeline"
+	"github.com/bartdeboer/pipeline/std"
+)
+
+// TrimPrefix produces each line of the pipe's contents with the leading
+// string prefix removed, if present. Lines without the prefix are produced
+// unchanged.
+func TrimPrefix(prefix string) pipeline.Program {
+	return std.FilterLine(func(line string) string {
+		return strings.TrimPrefix(line, prefix)
+	})
+}
+
+// TrimSpace produces each line of the pipe's contents with leading and
+// trailing white space removed, as defined by Unicode.
+func TrimSpace() pipeline.Program {
+	return std.FilterLine(strings.TrimSpace)
+}
+
+// TrimSuffix produces each line of the pipe's contents with the trailing
+// string suffix removed, if present. Lines without the suffix are produced
+// unchanged.
+func TrimSuffix(suffix string) pipeline.Program {
+	return std.FilterLine(func(line string) string {
+		return strings.TrimSuffix(line, suffix)
+	})
+}
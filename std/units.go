@@ -0,0 +1,122 @@
+package std
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// unit is a unit of measurement of some kind, such as length. A value v in
+// the unit is v*scale+offset in the base unit of its kind.
+type unit struct {
+	kind          string
+	scale, offset float64
+}
+
+// units is the registry of units known to ConvertUnits.
+var units = map[string]unit{
+	// Data, in bits
+	"bit": {"data", 1, 0},
+	"B":   {"data", 8, 0},
+	"kB":  {"data", 8e3, 0},
+	"MB":  {"data", 8e6, 0},
+	"GB":  {"data", 8e9, 0},
+	"TB":  {"data", 8e12, 0},
+	"KiB": {"data", 8 << 10, 0},
+	"MiB": {"data", 8 << 20, 0},
+	"GiB": {"data", 8 << 30, 0},
+	"TiB": {"data", 8 << 40, 0},
+	// Time, in seconds
+	"ns":  {"time", 1e-9, 0},
+	"us":  {"time", 1e-6, 0},
+	"ms":  {"time", 1e-3, 0},
+	"s":   {"time", 1, 0},
+	"min": {"time", 60, 0},
+	"h":   {"time", 3600, 0},
+	"d":   {"time", 86400, 0},
+	// Length, in metres
+	"mm": {"length", 1e-3, 0},
+	"cm": {"length", 1e-2, 0},
+	"m":  {"length", 1, 0},
+	"km": {"length", 1e3, 0},
+	"in": {"length", 0.0254, 0},
+	"ft": {"length", 0.3048, 0},
+	"mi": {"length", 1609.344, 0},
+	// Mass, in grams
+	"g":  {"mass", 1, 0},
+	"kg": {"mass", 1e3, 0},
+	"oz": {"mass", 28.349523125, 0},
+	"lb": {"mass", 453.59237, 0},
+	// Temperature, in kelvin
+	"K": {"temperature", 1, 0},
+	"C": {"temperature", 1, 273.15},
+	"F": {"temperature", 5.0 / 9, 273.15 - 32*5.0/9},
+}
+
+// ConvertUnits reads numeric values from column col of each line of input,
+// where the first column is column 1 and columns are delimited by Unicode
+// whitespace, and produces the line with the value converted from unit from
+// to unit to, rounded to 12 significant digits to hide any floating-point
+// error in the conversion. The rest of the line is unchanged, and lines
+// whose column col is missing or isn't a number are produced as they are.
+//
+// The supported units are:
+//
+//   - data: bit, B, kB, MB, GB, TB, KiB, MiB, GiB, TiB
+//   - time: ns, us, ms, s, min, h, d
+//   - length: mm, cm, m, km, in, ft, mi
+//   - mass: g, kg, oz, lb
+//   - temperature: C, F, K
+//
+// If either unit is unknown, or they measure different kinds of quantity,
+// the pipe's error status will be set.
+func ConvertUnits(col int, from, to string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	convert, err := unitConversion(from, to)
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			line := replaceColumn(scanner.Text(), col, func(field string) (string, bool) {
+				v, err := strconv.ParseFloat(field, 64)
+				if err != nil {
+					return "", false
+				}
+				return formatFloat(roundSignificant(convert(v), 12)), true
+			})
+			fmt.Fprintln(p.Stdout, line)
+		}
+		return scanner.Err()
+	}
+	return p
+}
+
+func unitConversion(from, to string) (func(float64) float64, error) {
+	f, ok := units[from]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", from)
+	}
+	t, ok := units[to]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", to)
+	}
+	if f.kind != t.kind {
+		return nil, fmt.Errorf("can't convert %s (%s) to %s (%s)", from, f.kind, to, t.kind)
+	}
+	return func(v float64) float64 {
+		return (v*f.scale + f.offset - t.offset) / t.scale
+	}, nil
+}
+
+// roundSignificant returns v rounded to n significant digits.
+func roundSignificant(v float64, n int) float64 {
+	r, err := strconv.ParseFloat(strconv.FormatFloat(v, 'g', n, 64), 64)
+	if err != nil {
+		return v
+	}
+	return r
+}
@@ -0,0 +1,126 @@
+package std
+
+import (
+	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+var (
+	urlPattern   = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"'\x60{}|\\^]+`)
+	emailPattern = regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9-]+(?:\.[a-z0-9-]+)*\.[a-z]{2,}\b`)
+)
+
+// DistinctURLs reads URLs from the pipe, one per line, and produces each
+// distinct URL only once, the first time it is seen. URLs are considered
+// equal if they differ only in the order of their query parameters. Lines
+// that can't be parsed as URLs are compared as plain strings.
+func DistinctURLs() pipeline.Program {
+	seen := map[string]bool{}
+	return pipeline.Scanner(func(line string, w io.Writer) {
+		key := line
+		if u, err := url.Parse(line); err == nil {
+			// Query.Encode sorts the parameters by key
+			u.RawQuery = u.Query().Encode()
+			key = u.String()
+		}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		fmt.Fprintln(w, line)
+	})
+}
+
+// ExtractEmails produces every email address found in the pipe's contents,
+// one per line, in the order they appear.
+func ExtractEmails() pipeline.Program {
+	return extract(emailPattern, func(s string) string { return s })
+}
+
+// ExtractURLs produces every http or https URL found in the pipe's contents,
+// one per line, in the order they appear, wherever they occur in the text.
+// Trailing punctuation, such as a full stop ending a sentence or a
+// parenthesis enclosing the URL, is not considered part of the URL.
+func ExtractURLs() pipeline.Program {
+	return extract(urlPattern, trimURL)
+}
+
+func extract(re *regexp.Regexp, clean func(string) string) pipeline.Program {
+	return pipeline.Scanner(func(line string, w io.Writer) {
+		for _, match := range re.FindAllString(line, -1) {
+			fmt.Fprintln(w, clean(match))
+		}
+	})
+}
+
+// trimURL removes trailing punctuation from a URL matched in prose, keeping
+// closing parentheses and brackets that are balanced within the URL.
+func trimURL(u string) string {
+	for {
+		trimmed := strings.TrimRight(u, ".,;:!?'\"")
+		switch {
+		case strings.HasSuffix(trimmed, ")") && strings.Count(trimmed, "(") < strings.Count(trimmed, ")"):
+			trimmed = trimmed[:len(trimmed)-1]
+		case strings.HasSuffix(trimmed, "]") && strings.Count(trimmed, "[") < strings.Count(trimmed, "]"):
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+		if trimmed == u {
+			return u
+		}
+		u = trimmed
+	}
+}
+
+// NormalizeEmails reads email addresses from the pipe, one per line, and
+// produces each one normalized: surrounding whitespace is trimmed, any
+// display name or angle brackets are removed, and the domain part is
+// lowercased. The local part is left as it is, since it may be case
+// sensitive. Empty lines and lines that aren't valid addresses, as
+// determined by [mail.ParseAddress], are skipped.
+func NormalizeEmails() pipeline.Program {
+	return pipeline.Scanner(func(line string, w io.Writer) {
+		addr, err := mail.ParseAddress(strings.TrimSpace(line))
+		if err != nil {
+			return
+		}
+		i := strings.LastIndex(addr.Address, "@")
+		fmt.Fprintln(w, addr.Address[:i]+strings.ToLower(addr.Address[i:]))
+	})
+}
+
+// ResolveURL reads URLs from the pipe, one per line, and produces each one
+// resolved against the URL base, as for [url.URL.ResolveReference]. Relative
+// references such as "/foo" or "../bar" become absolute URLs, while URLs that
+// are already absolute are produced unchanged. Surrounding whitespace is
+// trimmed, and empty lines and lines that can't be parsed as URLs are
+// skipped. If base can't be parsed, the pipe's error status will be set.
+func ResolveURL(base string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	baseURL, err := url.Parse(base)
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		scanner := newScanner(p.Stdin)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			ref, err := url.Parse(line)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintln(p.Stdout, baseURL.ResolveReference(ref))
+		}
+		return scanner.Err()
+	}
+	return p
+}
@@ -0,0 +1,137 @@
+package std
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bartdeboer/pipeline"
+)
+
+// utf8BOM is the UTF-8 encoding of the byte order mark, U+FEFF.
+const utf8BOM = "\xef\xbb\xbf"
+
+// AppendFileMode is like [std.AppendFile], but creates the file with the
+// permissions perm, such as 0o600 for a file of secrets. See [WriteFileMode]
+// for how perm interacts with the umask and existing files.
+func AppendFileMode(path string, perm os.FileMode) pipeline.Program {
+	return writeFileMode(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, perm)
+}
+
+// WriteFileMode is like [std.WriteFile], but creates the file with the
+// permissions perm, such as 0o755 for an executable script. As for
+// [os.OpenFile], the permissions of a new file are reduced by the process's
+// umask, so with the common umask 022, 0o777 gives 0o755. If the file
+// already exists, its permissions are changed to perm, which isn't subject
+// to the umask, so that the file always ends up with the requested mode.
+func WriteFileMode(path string, perm os.FileMode) pipeline.Program {
+	return writeFileMode(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, perm)
+}
+
+func writeFileMode(path string, flag int, perm os.FileMode) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		if _, err := os.Stat(path); err == nil {
+			if err := os.Chmod(path, perm); err != nil {
+				fmt.Fprint(p.Stdout, 0)
+				return p.SetError(err)
+			}
+		}
+		written, err := writeFile(p.Stdin, path, flag, perm)
+		fmt.Fprint(p.Stdout, written)
+		return p.SetError(err)
+	}
+	return p
+}
+
+// TeeAppendFile is like [TeeFile], but appends to each file instead of
+// truncating it.
+func TeeAppendFile(paths ...string) pipeline.Program {
+	return teeFile(os.O_APPEND|os.O_CREATE|os.O_WRONLY, paths)
+}
+
+// TeeFile copies the pipe's contents to its output, and also writes them to
+// each of the files paths, creating or truncating them, like Unix tee(1).
+// The files are closed when the input is exhausted. If any file can't be
+// opened or written, the pipe's error status will be set.
+func TeeFile(paths ...string) pipeline.Program {
+	return teeFile(os.O_RDWR|os.O_CREATE|os.O_TRUNC, paths)
+}
+
+func teeFile(flag int, paths []string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		files := make([]*os.File, 0, len(paths))
+		defer func() {
+			for _, f := range files {
+				f.Close()
+			}
+		}()
+		writers := []io.Writer{p.Stdout}
+		for _, path := range paths {
+			f, err := os.OpenFile(path, flag, 0o666)
+			if err != nil {
+				return p.Exit(err)
+			}
+			files = append(files, f)
+			writers = append(writers, f)
+		}
+		if _, err := io.Copy(io.MultiWriter(writers...), p.Stdin); err != nil {
+			return p.Exit(err)
+		}
+		for _, f := range files {
+			if err := f.Close(); err != nil {
+				return p.Exit(err)
+			}
+		}
+		files = nil
+		return nil
+	}
+	return p
+}
+
+// WriteFileAll is like [std.WriteFile], but first creates the file's parent
+// directory, along with any necessary parents, with permissions 0o755 (before
+// umask), like mkdir -p. If the directory can't be created, no bytes are
+// written, and the pipe's error status will be set.
+func WriteFileAll(path string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			fmt.Fprint(p.Stdout, 0)
+			return p.SetError(err)
+		}
+		written, err := writeFile(p.Stdin, path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+		fmt.Fprint(p.Stdout, written)
+		return err
+	}
+	return p
+}
+
+// WriteFileBOM is like [std.WriteFile], but writes a UTF-8 byte order mark
+// (EF BB BF) to the file before the pipe's contents, as some Windows tools,
+// such as Excel, require to read UTF-8 text correctly. The number of bytes
+// written includes the BOM.
+func WriteFileBOM(path string) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		r := io.MultiReader(strings.NewReader(utf8BOM), p.Stdin)
+		written, err := writeFile(r, path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+		fmt.Fprint(p.Stdout, written)
+		return err
+	}
+	return p
+}
+
+// writeFile copies r to the file path, opened with flag and created with perm
+// if necessary, and returns the number of bytes written.
+func writeFile(r io.Reader, path string, flag int, perm os.FileMode) (int64, error) {
+	out, err := os.OpenFile(path, flag, perm)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+	return io.Copy(out, r)
+}
@@ -0,0 +1,17 @@
+// Package main is a Go plugin used to test PluginFilter. It exports a Filter
+// function converting its input to upper case.
+package main
+
+import (
+	"bytes"
+	"io"
+)
+
+func Filter(r io.Reader, w io.Writer) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bytes.ToUpper(data))
+	return err
+}
@@ -0,0 +1,8 @@
+module github.com/bartdeboer/script/v2/yaml
+
+go 1.22.1
+
+require (
+	github.com/bartdeboer/pipeline v0.0.3
+	gopkg.in/yaml.v3 v3.0.1
+)
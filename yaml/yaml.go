@@ -0,0 +1,138 @@
+package yaml
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bartdeboer/pipeline"
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLToJSON converts the pipe's contents (presumed to be YAML) to JSON.
+// Each YAML document in the input, separated by ---, produces one line of
+// compact JSON, so a multi-document input produces JSON Lines (NDJSON)
+// rather than a JSON array. Object keys are sorted. If the input isn't valid
+// YAML, or contains values that can't be represented in JSON, the pipe's
+// error status will be set.
+func YAMLToJSON() pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		dec := yaml.NewDecoder(p.Stdin)
+		for {
+			var doc interface{}
+			err := dec.Decode(&doc)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return p.Exit(err)
+			}
+			v, err := jsonValue(doc)
+			if err != nil {
+				return p.Exit(err)
+			}
+			data, err := json.Marshal(v)
+			if err != nil {
+				return p.Exit(err)
+			}
+			fmt.Fprintln(p.Stdout, string(data))
+		}
+	}
+	return p
+}
+
+// JSONToYAML converts the pipe's contents (presumed to be a stream of JSON
+// values, such as a single JSON document or JSON Lines) to YAML. Each JSON
+// value produces one YAML document, with documents separated by ---. If
+// the input isn't valid JSON, the pipe's error status will be set.
+func JSONToYAML() pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		dec := json.NewDecoder(p.Stdin)
+		dec.UseNumber()
+		enc := yaml.NewEncoder(p.Stdout)
+		enc.SetIndent(2)
+		for {
+			var v interface{}
+			err := dec.Decode(&v)
+			if errors.Is(err, io.EOF) {
+				return enc.Close()
+			}
+			if err != nil {
+				return p.Exit(err)
+			}
+			if err := enc.Encode(yamlValue(v)); err != nil {
+				return p.Exit(err)
+			}
+		}
+	}
+	return p
+}
+
+// jsonValue converts a value decoded from YAML to one that can be encoded
+// as JSON, turning maps with non-string keys into maps keyed by the string
+// form of each key.
+func jsonValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			e, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = e
+		}
+		return v, nil
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			e, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+				return nil, fmt.Errorf("unsupported YAML map key %v: must be a scalar", k)
+			}
+			m[fmt.Sprint(k)] = e
+		}
+		return m, nil
+	case []interface{}:
+		for i, e := range v {
+			e, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = e
+		}
+		return v, nil
+	}
+	return v, nil
+}
+
+// yamlValue converts a value decoded from JSON to one that encodes as the
+// equivalent YAML, turning numbers into integers where they fit, and into
+// floats otherwise.
+func yamlValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = yamlValue(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = yamlValue(e)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	}
+	return v
+}
@@ -0,0 +1,55 @@
+package yaml_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bartdeboer/pipeline"
+	"github.com/bartdeboer/script/v2/yaml"
+)
+
+func TestYAMLToJSONProducesOneLinePerDocument(t *testing.T) {
+	t.Parallel()
+	input := "kind: Pod\nspec:\n  replicas: 2\n  ports: [80, 443]\n---\nname: b\nenabled: true\nempty: null\n1: one\n"
+	want := "{\"kind\":\"Pod\",\"spec\":{\"ports\":[80,443],\"replicas\":2}}\n{\"1\":\"one\",\"empty\":null,\"enabled\":true,\"name\":\"b\"}\n"
+	p := pipeline.NewPipeline().WithReader(strings.NewReader(input))
+	got, err := p.Pipe(yaml.YAMLToJSON()).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestYAMLToJSONSetsErrorGivenInvalidYAML(t *testing.T) {
+	t.Parallel()
+	p := pipeline.NewPipeline().WithReader(strings.NewReader("a: [1, 2\n"))
+	_, err := p.Pipe(yaml.YAMLToJSON()).String()
+	if err == nil {
+		t.Error("want error given invalid YAML")
+	}
+}
+
+func TestJSONToYAMLProducesOneDocumentPerValue(t *testing.T) {
+	t.Parallel()
+	input := "{\"name\":\"a\",\"size\":1.50,\"tags\":[\"x\",\"z\"],\"count\":3}\n{\"name\":\"b\"}\n"
+	want := "count: 3\nname: a\nsize: 1.5\ntags:\n  - x\n  - z\n---\nname: b\n"
+	p := pipeline.NewPipeline().WithReader(strings.NewReader(input))
+	got, err := p.Pipe(yaml.JSONToYAML()).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestJSONToYAMLSetsErrorGivenInvalidJSON(t *testing.T) {
+	t.Parallel()
+	p := pipeline.NewPipeline().WithReader(strings.NewReader("{\"a\":"))
+	_, err := p.Pipe(yaml.JSONToYAML()).String()
+	if err == nil {
+		t.Error("want error given invalid JSON")
+	}
+}
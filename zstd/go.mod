@@ -0,0 +1,8 @@
+module github.com/bartdeboer/script/v2/zstd
+
+go 1.22.1
+
+require (
+	github.com/bartdeboer/pipeline v0.0.3
+	github.com/klauspost/compress v1.17.11
+)
@@ -0,0 +1,58 @@
+package zstd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bartdeboer/pipeline"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Unzstd decompresses the pipe's contents as zstd data. Input consisting of
+// several concatenated frames is decompressed as a single stream. If the
+// input isn't valid zstd data, the pipe's error status will be set.
+func Unzstd() pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	p.StartFn = func() error {
+		zr, err := zstd.NewReader(p.Stdin)
+		if err != nil {
+			return p.Exit(err)
+		}
+		defer zr.Close()
+		if _, err := io.Copy(p.Stdout, zr); err != nil {
+			return p.Exit(err)
+		}
+		return nil
+	}
+	return p
+}
+
+// Zstd compresses the pipe's contents as zstd data at the given compression
+// level, from 1 (fastest) to 22 (best compression), as understood by the
+// zstd command; levels are mapped to the nearest level supported by
+// [github.com/klauspost/compress/zstd]. The input is streamed, so it need
+// not fit in memory, and the frame is completed when the input ends. If
+// level is out of range, the pipe's error status will be set.
+func Zstd(level int) pipeline.Program {
+	p := pipeline.NewBaseProgram()
+	var err error
+	if level < 1 || level > 22 {
+		err = fmt.Errorf("invalid zstd compression level %d: must be 1 to 22", level)
+	}
+	p.SetError(err)
+	p.StartFn = func() error {
+		if err != nil {
+			return err
+		}
+		zw, err := zstd.NewWriter(p.Stdout, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		if err != nil {
+			return p.Exit(err)
+		}
+		if _, err := io.Copy(zw, p.Stdin); err != nil {
+			zw.Close()
+			return p.Exit(err)
+		}
+		return zw.Close()
+	}
+	return p
+}
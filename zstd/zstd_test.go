@@ -0,0 +1,51 @@
+package zstd_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bartdeboer/pipeline"
+	"github.com/bartdeboer/script/v2/zstd"
+)
+
+func TestZstdOutputIsDecompressedByUnzstd(t *testing.T) {
+	t.Parallel()
+	want := strings.Repeat("hello, world\n", 1000)
+	for _, level := range []int{1, 3, 19} {
+		p := pipeline.NewPipeline().WithReader(strings.NewReader(want))
+		compressed, err := p.Pipe(zstd.Zstd(level)).Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(compressed) >= len(want) {
+			t.Errorf("level %d: want compressed data smaller than %d bytes, got %d", level, len(want), len(compressed))
+		}
+		p = pipeline.NewPipeline().WithReader(bytes.NewReader(compressed))
+		got, err := p.Pipe(zstd.Unzstd()).String()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want != got {
+			t.Errorf("level %d: round trip changed data", level)
+		}
+	}
+}
+
+func TestZstdSetsErrorGivenInvalidLevel(t *testing.T) {
+	t.Parallel()
+	p := pipeline.NewPipeline().WithReader(strings.NewReader("data"))
+	_, err := p.Pipe(zstd.Zstd(23)).String()
+	if err == nil {
+		t.Error("want error given invalid compression level")
+	}
+}
+
+func TestUnzstdSetsErrorGivenInvalidInput(t *testing.T) {
+	t.Parallel()
+	p := pipeline.NewPipeline().WithReader(strings.NewReader("not zstd"))
+	_, err := p.Pipe(zstd.Unzstd()).String()
+	if err == nil {
+		t.Error("want error given invalid zstd data")
+	}
+}